@@ -8,6 +8,8 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
 	"log/slog"
 	"net/http"
 	"os"
@@ -17,11 +19,50 @@ import (
 
 func main() {
 	// Парсим флаги командной строки для получения пути конфиг.yaml файла
-	configPath := flag.String("config", "configs/config.yaml", "path to configuration file")
+	configPath := flag.String("config", "configs/config.yaml", "path to configuration file (empty to use only env and flags)")
+	checkConfig := flag.Bool("check-config", false, "validate configuration file and exit")
+	printConfig := flag.Bool("print-config", false, "print effective configuration (flags > env > file > defaults) and exit")
+
+	// Флаги для переопределения каждого поля конфигурации, например --server.port=9090
+	overrides := make(config.Overrides)
+	for _, f := range config.Fields() {
+		path := f.Path
+		flag.Func(f.Flag, f.Usage(), func(value string) error {
+			overrides[path] = value
+			return nil
+		})
+	}
 	flag.Parse()
 
 	// Загружаем конфигурацию
-	cfg, err := config.LoadConfig(*configPath)
+	cfg, err := config.Load(*configPath, overrides)
+	if *checkConfig || *printConfig {
+		// Режим проверки: выводим все найденные проблемы и завершаемся
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if *printConfig {
+			// Секреты не выводим, чтобы они не попали в логи деплоя
+			if cfg.Admin.Token != "" {
+				cfg.Admin.Token = "******"
+			}
+			if cfg.RateLimiter.Redis.Password != "" {
+				cfg.RateLimiter.Redis.Password = "******"
+			}
+			if cfg.RateLimiter.Auth.JWT.Secret != "" {
+				cfg.RateLimiter.Auth.JWT.Secret = "******"
+			}
+			for clientID := range cfg.RateLimiter.Auth.APIKeys {
+				cfg.RateLimiter.Auth.APIKeys[clientID] = "******"
+			}
+			out, _ := yaml.Marshal(cfg)
+			fmt.Print(string(out))
+			return
+		}
+		fmt.Printf("configuration %s is valid\n", *configPath)
+		return
+	}
 	if err != nil {
 		slog.Error("Error loading config", "error", err)
 		os.Exit(1)
@@ -52,13 +93,34 @@ func main() {
 		cfg.RateLimiter.Interval,
 	)
 	healthChecker := background.NewHealthChecker(
-		server.GetBackends(),
+		server.GetBalancer(),
 		cfg.HealthChecker.Interval,
 		cfg.HealthChecker.Path,
 	)
 
+	// Перезагрузка конфигурации по SIGHUP или при изменении файла
+	var configWatcher *background.ConfigWatcher
+	configWatcher = background.NewConfigWatcher(*configPath, cfg.Reload.PollInterval, func() error {
+		newCfg, err := config.Load(*configPath, overrides)
+		if err != nil {
+			return err
+		}
+
+		if err = server.ApplyConfig(newCfg); err != nil {
+			return err
+		}
+
+		healthChecker.SetInterval(newCfg.HealthChecker.Interval)
+		healthChecker.SetPath(newCfg.HealthChecker.Path)
+		tokenRefill.SetInterval(newCfg.RateLimiter.Interval)
+		configWatcher.SetInterval(newCfg.Reload.PollInterval)
+
+		return nil
+	})
+
 	tokenRefill.Start(ctx)
 	healthChecker.Start(ctx)
+	configWatcher.Start(ctx)
 
 	// Канал для получения сигналов операционной системы
 	sigChan := make(chan os.Signal, 1)
@@ -84,9 +146,15 @@ func main() {
 	// Ожидаем завершения фоновых процессов
 	tokenRefill.Wait()
 	healthChecker.Wait()
+	configWatcher.Wait()
 
 	// Останавливаем сервер
 	if err = server.Shutdown(); err != nil {
 		slog.Error("Error shutting down server", "error", err)
 	}
+
+	// Дожидаемся завершения оставшихся соединений с бэкендами (например, WebSocket)
+	if err = server.DrainBackends(cfg.Server.DrainTimeout); err != nil {
+		slog.Error("Error draining backends", "error", err)
+	}
 }
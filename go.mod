@@ -2,9 +2,12 @@ module CloudCamp
 
 go 1.21
 
+require (
+	github.com/stretchr/testify v1.10.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/testify v1.10.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
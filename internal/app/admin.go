@@ -0,0 +1,106 @@
+package app
+
+import (
+	"CloudCamp/internal/config"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// listenAdmin открывает listener для управляющих маршрутов: TCP или unix-сокет, при необходимости с TLS
+func listenAdmin(cfg config.AdminConfig) (net.Listener, error) {
+	var (
+		ln  net.Listener
+		err error
+	)
+
+	if cfg.IsUnix() {
+		path := cfg.SocketPath()
+
+		// Удаляем сокет, оставшийся от предыдущего запуска
+		if info, statErr := os.Stat(path); statErr == nil && info.Mode()&fs.ModeSocket != 0 {
+			_ = os.Remove(path)
+		}
+
+		ln, err = net.Listen("unix", path)
+		if err == nil {
+			// Доступ к сокету только у владельца процесса
+			err = os.Chmod(path, 0600)
+		}
+	} else {
+		ln, err = net.Listen("tcp", cfg.Addr)
+	}
+	if err != nil {
+		if ln != nil {
+			_ = ln.Close()
+		}
+		return nil, fmt.Errorf("failed to listen on admin address %s: %w", cfg.Addr, err)
+	}
+
+	if cfg.TLS.CertFile == "" {
+		return ln, nil
+	}
+
+	tlsConfig, err := adminTLSConfig(cfg.TLS)
+	if err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	return tls.NewListener(ln, tlsConfig), nil
+}
+
+// adminTLSConfig создает настройки TLS admin listener. При заданном client_ca_file
+// клиент обязан предъявить сертификат, подписанный этим CA
+func adminTLSConfig(cfg config.AdminTLSConfig) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load admin TLS certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read admin client CA: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("admin client CA file contains no certificates")
+		}
+
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+// openAuditLog возвращает логгер журнала аудита. Без указанного файла записи идут в общий лог приложения
+func openAuditLog(path string) (*slog.Logger, io.Closer, error) {
+	if path == "" {
+		return slog.Default().With(slog.String("log", "audit")), io.NopCloser(nil), nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create audit log dir: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	return slog.New(slog.NewJSONHandler(file, nil)), file, nil
+}
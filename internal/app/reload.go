@@ -0,0 +1,256 @@
+package app
+
+import (
+	balancerDir "CloudCamp/internal/balancer"
+	"CloudCamp/internal/config"
+	"CloudCamp/internal/domain/balancerDomain"
+	"CloudCamp/internal/limiter"
+	"CloudCamp/pkg/utils"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"reflect"
+	"slices"
+)
+
+// ApplyConfig применяет новую конфигурацию без перезапуска и разрыва текущих соединений:
+// изменяет пул бэкендов, при необходимости заменяет стратегию, обновляет лимиты клиентов
+// и настройки проксирования. Изменения, требующие перезапуска, только логируются
+func (s *Server) ApplyConfig(newCfg *config.Config) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.cfg
+
+	// Проверяем, что стратегию можно построить, до того как что-либо менять
+	if _, err := balancerDir.NewStrategy(newCfg, nil); err != nil {
+		return err
+	}
+
+	// Политики собираются заранее, чтобы ошибка чтения ключа JWT не оставила конфигурацию примененной частично
+	var policies *limiter.PolicySet
+	if policiesChanged(old.RateLimiter, newCfg.RateLimiter) {
+		var err error
+		if policies, err = limiter.NewPolicySet(newCfg.RateLimiter); err != nil {
+			return err
+		}
+	}
+
+	var resolver *utils.ClientIPResolver
+	if !slices.Equal(old.Server.TrustedProxies, newCfg.Server.TrustedProxies) || old.Server.ForwardedHeader != newCfg.Server.ForwardedHeader {
+		var err error
+		if resolver, err = utils.NewClientIPResolver(newCfg.Server.TrustedProxies, newCfg.Server.ForwardedHeader); err != nil {
+			return err
+		}
+	}
+
+	s.applyBackends(old, newCfg)
+
+	if old.Balancer.Strategy != newCfg.Balancer.Strategy || old.Balancer.ConsistentHash != newCfg.Balancer.ConsistentHash {
+		err := s.balancer.Swap(func(backends []*balancerDomain.Backend) (balancerDomain.Strategy, error) {
+			return balancerDir.NewStrategy(newCfg, backends)
+		})
+		if err != nil {
+			return err
+		}
+		slog.Info("balancing strategy changed",
+			slog.String("from", old.Balancer.Strategy),
+			slog.String("to", newCfg.Balancer.Strategy),
+		)
+	}
+
+	if err := s.applyRateLimits(old, newCfg); err != nil {
+		return err
+	}
+	if policies != nil {
+		s.rateLimiting.SetPolicies(policies)
+		slog.Info("rate limit policies changed", slog.Int("count", len(newCfg.RateLimiter.Policies)))
+	}
+
+	if resolver != nil {
+		s.clientIP.SetResolver(resolver)
+		slog.Info("trusted proxies changed",
+			slog.Any("trusted_proxies", newCfg.Server.TrustedProxies),
+			slog.String("forwarded_header", newCfg.Server.ForwardedHeader),
+		)
+	}
+
+	if !reflect.DeepEqual(old.Concurrency, newCfg.Concurrency) {
+		s.concurrency.UpdateConfig(newCfg.Concurrency)
+		s.admission.UpdateConfig(newCfg.Concurrency)
+		slog.Info("concurrency limits changed",
+			slog.Bool("enabled", newCfg.Concurrency.Enabled),
+			slog.Int("per_client", newCfg.Concurrency.PerClient),
+			slog.Int("per_backend", newCfg.Concurrency.PerBackend),
+			slog.Int("queue_max_size", newCfg.Concurrency.Queue.MaxSize),
+			slog.Bool("adaptive", newCfg.Concurrency.Adaptive.Enabled),
+		)
+	}
+
+	// Обработчики появляются только после запуска сервера
+	if s.proxyHandler != nil {
+		s.proxyHandler.UpdateConfig(newCfg.Proxy)
+	}
+	if s.backendHandler != nil {
+		s.backendHandler.UpdateConfig(newCfg)
+	}
+	if s.adminAuth != nil && old.Admin.Token != newCfg.Admin.Token {
+		s.adminAuth.SetToken(newCfg.Admin.Token)
+		slog.Info("admin token changed")
+	}
+
+	if old.Server.Port != newCfg.Server.Port || old.Log != newCfg.Log || old.Env != newCfg.Env {
+		slog.Warn("server port, environment and log settings require restart to take effect")
+	}
+	if old.RateLimiter.Store != newCfg.RateLimiter.Store || old.RateLimiter.Backend != newCfg.RateLimiter.Backend || old.RateLimiter.Redis != newCfg.RateLimiter.Redis {
+		slog.Warn("client store and rate limiter backend settings require restart to take effect")
+	}
+	if old.Admin.Addr != newCfg.Admin.Addr || old.Admin.TLS != newCfg.Admin.TLS || old.Admin.AuditLog != newCfg.Admin.AuditLog {
+		slog.Warn("admin address, TLS and audit log settings require restart to take effect")
+	}
+
+	s.cfg = newCfg
+	return nil
+}
+
+// applyBackends применяет к текущему пулу разницу между старым и новым списком бэкендов.
+// Неизмененные бэкенды сохраняются вместе со счетчиками соединений и состоянием,
+// бэкенды, добавленные через admin API, не затрагиваются
+func (s *Server) applyBackends(old, newCfg *config.Config) {
+	oldByURL := make(map[string]config.BackendConfig)
+	for _, bc := range old.Balancer.Backends {
+		oldByURL[bc.URL] = bc
+	}
+	newByURL := make(map[string]config.BackendConfig)
+	for _, bc := range newCfg.Balancer.Backends {
+		newByURL[bc.URL] = bc
+	}
+
+	settingsChanged := old.HealthChecker.Rise != newCfg.HealthChecker.Rise ||
+		old.HealthChecker.Fall != newCfg.HealthChecker.Fall ||
+		old.Balancer.CircuitBreaker != newCfg.Balancer.CircuitBreaker ||
+		old.Concurrency.Enabled != newCfg.Concurrency.Enabled ||
+		old.Concurrency.PerBackend != newCfg.Concurrency.PerBackend ||
+		old.Concurrency.Adaptive != newCfg.Concurrency.Adaptive
+
+	s.balancer.ModifyBackends(func(current []*balancerDomain.Backend) []*balancerDomain.Backend {
+		updated := make([]*balancerDomain.Backend, 0, len(newCfg.Balancer.Backends))
+		seen := make(map[string]bool)
+
+		for _, b := range current {
+			_, inOld := oldByURL[b.URL]
+			bc, inNew := newByURL[b.URL]
+			seen[b.URL] = true
+
+			switch {
+			case inOld && !inNew:
+				slog.Info("backend removed from pool", slog.String("id", b.ID), slog.String("url", b.URL))
+			case inNew && !sameBackend(b, bc):
+				updated = append(updated, balancerDir.NewBackendFromConfig(newCfg, bc))
+				slog.Info("backend updated", slog.String("id", b.ID), slog.String("url", b.URL))
+			default:
+				if settingsChanged {
+					balancerDir.ApplyBackendSettings(newCfg, b)
+				}
+				updated = append(updated, b)
+			}
+		}
+
+		for _, bc := range newCfg.Balancer.Backends {
+			if !seen[bc.URL] {
+				b := balancerDir.NewBackendFromConfig(newCfg, bc)
+				updated = append(updated, b)
+				slog.Info("backend added to pool", slog.String("id", b.ID), slog.String("url", b.URL))
+			}
+		}
+
+		return updated
+	})
+}
+
+// sameBackend проверяет, совпадают ли идентификатор и вес бэкенда с конфигурацией
+func sameBackend(b *balancerDomain.Backend, bc config.BackendConfig) bool {
+	id := bc.ID
+	if id == "" {
+		if u, err := url.Parse(bc.URL); err == nil && u.Host != "" {
+			id = u.Host
+		} else {
+			id = bc.URL
+		}
+	}
+
+	weight := bc.Weight
+	if weight < 1 {
+		weight = 1
+	}
+
+	return b.ID == id && b.Weight == weight
+}
+
+// policiesChanged проверяет, изменились ли политики лимитов или настройки аутентификации клиентов
+func policiesChanged(old, newRL config.RateLimitConfig) bool {
+	return old.Enabled != newRL.Enabled ||
+		!reflect.DeepEqual(old.Policies, newRL.Policies) ||
+		!reflect.DeepEqual(old.Auth, newRL.Auth)
+}
+
+// applyRateLimits применяет изменения глобального лимита и лимитов клиентов из конфигурации.
+// Клиенты, созданные через API, и клиенты, переопределенные записями хранилища, не затрагиваются
+func (s *Server) applyRateLimits(old, newCfg *config.Config) error {
+	oldRL, newRL := old.RateLimiter, newCfg.RateLimiter
+
+	if oldRL.Algorithm != newRL.Algorithm {
+		if err := s.limiter.SetAlgorithm("global", newRL.Algorithm); err != nil {
+			return err
+		}
+		slog.Info("rate limit algorithm changed", slog.String("algorithm", newRL.Algorithm))
+	}
+
+	if !newRL.Enabled {
+		if oldRL.Enabled {
+			s.limiter.RemoveClientLimit("global")
+			for clientID := range oldRL.Clients {
+				if !s.isStoredClient(clientID) {
+					s.limiter.RemoveClientLimit(clientID)
+				}
+			}
+			slog.Info("rate limiter disabled")
+		}
+		return nil
+	}
+
+	if !oldRL.Enabled || oldRL.Rate != newRL.Rate || oldRL.Period != newRL.Period {
+		if err := s.limiter.SetLimit("global", newRL.Rate, newRL.Period); err != nil {
+			return err
+		}
+		slog.Info("global rate limit changed", slog.Int("rate", newRL.Rate), slog.Duration("period", newRL.Period))
+	}
+
+	if oldRL.Enabled {
+		for clientID := range oldRL.Clients {
+			if _, ok := newRL.Clients[clientID]; !ok && !s.isStoredClient(clientID) {
+				s.limiter.RemoveClientLimit(clientID)
+				slog.Info("client limit removed", slog.String("client_id", clientID))
+			}
+		}
+	}
+
+	for clientID, limit := range newRL.Clients {
+		if prev, ok := oldRL.Clients[clientID]; oldRL.Enabled && ok && prev == limit {
+			continue
+		}
+		if s.isStoredClient(clientID) {
+			slog.Info("client limit is overridden by store, config change ignored", slog.String("client_id", clientID))
+			continue
+		}
+		if err := s.limiter.SetAlgorithm(clientID, limit.Algorithm); err != nil {
+			return fmt.Errorf("failed to set client algorithm for %s: %w", clientID, err)
+		}
+		if err := s.limiter.SetClientLimit(clientID, limit.RateLimit, limit.Period); err != nil {
+			return fmt.Errorf("failed to set client limit for %s: %w", clientID, err)
+		}
+		slog.Info("client limit changed", slog.String("client_id", clientID))
+	}
+
+	return nil
+}
@@ -2,34 +2,60 @@ package app
 
 import (
 	"CloudCamp/internal/handler"
+	"CloudCamp/internal/metrics"
+	"log/slog"
 	"net/http"
 )
 
-// setupRoutes настраивает маршруты для сервера
+// setupRoutes настраивает маршруты публичного сервера: через него проходит только проксируемый трафик
 func (s *Server) setupRoutes() {
 	// Создаем обработчики
-	proxyHandler := handler.NewProxyHandler(s.balancer)
-	clientHandler := handler.NewClientHandler(s.limiter)
-	rateLimiterMiddleware := handler.NewRateLimiterMiddleware(s.limiter)
+	s.proxyHandler = handler.NewProxyHandler(s.balancer, s.cfg.Proxy)
+	s.proxyHandler.SetAdmissionQueue(s.admission)
+	concurrency := handler.NewConcurrencyMiddleware(s.concurrency)
 
 	// Настраиваем маршруты
 	mux := http.NewServeMux()
 
 	// Маршрут для прокси
-	mux.HandleFunc("/", proxyHandler.ServeHTTP)
+	mux.HandleFunc("/", s.proxyHandler.ServeHTTP)
+
+	// Оборачиваем все маршруты в middleware для rate limiting и ограничения одновременных запросов;
+	// адрес клиента определяется до проверки лимитов
+	s.httpServer.Handler = s.clientIP.Middleware(s.rateLimiting.Middleware(concurrency.Middleware(mux)))
+}
+
+// setupAdminRoutes настраивает управляющие маршруты admin-сервера. Они не проходят через rate limiter,
+// требуют аутентификации, а каждое изменение записывается в журнал аудита
+func (s *Server) setupAdminRoutes(auditLogger *slog.Logger) {
+	// Создаем обработчики
+	s.backendHandler = handler.NewBackendHandler(s.balancer, s.cfg)
+	clientHandler := handler.NewClientHandler(s.limiter, s.clientStore)
+	auditMiddleware := handler.NewAuditMiddleware(auditLogger)
+
+	// Настраиваем маршруты
+	mux := http.NewServeMux()
 
 	// Маршруты для управления клиентами
-	mux.HandleFunc("/clients", func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodPost:
-			clientHandler.CreateClient(w, r)
-		case http.MethodDelete:
-			clientHandler.DeleteClient(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		}
-	})
-
-	// Оборачиваем все маршруты в middleware для rate limiting
-	s.httpServer.Handler = rateLimiterMiddleware.Middleware(mux)
+	mux.Handle("/clients", clientHandler)
+	mux.Handle("/clients/", clientHandler)
+
+	// Маршруты для управления пулом бэкендов
+	mux.Handle("/admin/backends", s.backendHandler)
+	mux.Handle("/admin/backends/", s.backendHandler)
+
+	// Состояние очереди запросов, ожидающих бэкенд
+	mux.Handle("/admin/queue", handler.NewQueueHandler(s.admission))
+
+	// Метрики в формате Prometheus
+	metrics.RegisterBackends(s.balancer)
+	if s.redisLimiter == nil {
+		// Для распределенного лимитера локальные бакеты не отражают реального состояния
+		metrics.RegisterBuckets(s.limiter)
+	}
+	metrics.RegisterAdmissionQueue(s.admission)
+	mux.Handle("/metrics", metrics.Handler())
+
+	// Сначала аутентификация, затем аудит, чтобы в журнал попадал субъект запроса
+	s.adminServer.Handler = s.adminAuth.Middleware(auditMiddleware.Middleware(mux))
 }
@@ -4,47 +4,137 @@ import (
 	balancerDir "CloudCamp/internal/balancer"
 	"CloudCamp/internal/config"
 	"CloudCamp/internal/domain/balancerDomain"
+	limiterDomain "CloudCamp/internal/domain/limiter"
+	"CloudCamp/internal/handler"
 	"CloudCamp/internal/limiter"
+	"CloudCamp/pkg/utils"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"sync"
 	"time"
 )
 
+// defaultDrainTimeout время ожидания завершения соединений с бэкендами по умолчанию
+const defaultDrainTimeout = 30 * time.Second
+
 // Server представляет собой HTTP-сервер с балансировщиком нагрузки
 type Server struct {
-	cfg        *config.Config
-	balancer   balancerDomain.Strategy
-	limiter    *limiter.MemoryRateLimiter
-	httpServer *http.Server
+	mu             sync.Mutex // сериализует применение новой конфигурации
+	cfg            *config.Config
+	balancer       *balancerDir.SwitchableBalancer
+	limiter        *limiter.MemoryRateLimiter // настройки лимитов и локальные бакеты
+	rateLimiter    limiterDomain.RateLimiter  // лимитер, проверяющий запросы: локальный или распределенный
+	redisLimiter   *limiter.RedisRateLimiter  // распределенный лимитер, nil для backend: memory
+	clientStore    limiterDomain.ClientStore  // хранилище клиентов, созданных через API, nil для memory
+	httpServer     *http.Server
+	adminServer    *http.Server // отдельный сервер управляющих маршрутов, nil если admin.addr не задан
+	adminListener  net.Listener
+	auditLog       io.Closer
+	proxyHandler   *handler.ProxyHandler
+	backendHandler *handler.BackendHandler
+	adminAuth      *handler.AdminAuthMiddleware
+	rateLimiting   *handler.RateLimiterMiddleware // проверка лимитов на публичном порту, включая политики
+	clientIP       *handler.ClientIPMiddleware    // определение адреса клиента с учетом доверенных прокси
+	concurrency    *limiter.ConcurrencyLimiter    // лимит одновременных запросов клиентов
+	admission      *limiter.AdmissionQueue        // очередь запросов, ожидающих свободный бэкенд
 }
 
 // NewServer создает новый сервер
 func NewServer(cfg *config.Config) (*Server, error) {
 	// Создаем балансировщик через фабрику
-	balancer := balancerDir.NewBalancerFactory(cfg)
-	if balancer == nil {
-		return nil, fmt.Errorf("unsupported balancing strategy: %s", cfg.Balancer.Strategy)
+	balancer, err := balancerDir.NewBalancerFactory(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	// Создаем rate limiter
 	rl := limiter.NewMemoryRateLimiter()
 
-	return &Server{
-		cfg:      cfg,
-		balancer: balancer,
-		limiter:  rl,
-	}, nil
+	s := &Server{
+		cfg:         cfg,
+		balancer:    balancerDir.NewSwitchableBalancer(balancer),
+		limiter:     rl,
+		rateLimiter: rl,
+	}
+
+	// При распределенном лимитере бакеты хранятся в Redis, а настройки лимитов — в локальном лимитере
+	if cfg.RateLimiter.Backend == config.LimiterBackendRedis {
+		s.redisLimiter = limiter.NewRedisRateLimiter(cfg.RateLimiter.Redis, rl)
+		s.rateLimiter = s.redisLimiter
+	}
+
+	// Адрес клиента берется из заголовков прокси, только если соединение пришло от доверенного прокси
+	resolver, err := utils.NewClientIPResolver(cfg.Server.TrustedProxies, cfg.Server.ForwardedHeader)
+	if err != nil {
+		return nil, err
+	}
+	s.clientIP = handler.NewClientIPMiddleware(resolver)
+
+	// Политики лимитов проверяются вместе с глобальным и клиентскими лимитами
+	policies, err := limiter.NewPolicySet(cfg.RateLimiter)
+	if err != nil {
+		return nil, err
+	}
+	s.rateLimiting = handler.NewRateLimiterMiddleware(s.rateLimiter)
+	s.rateLimiting.SetPolicies(policies)
+
+	// Число одновременных запросов ограничивается независимо от частоты
+	s.concurrency = limiter.NewConcurrencyLimiter(cfg.Concurrency)
+	s.admission = limiter.NewAdmissionQueue(cfg.Concurrency)
+
+	// Открываем хранилище клиентов, созданных через API
+	if cfg.RateLimiter.Store.Type == config.ClientStoreFile {
+		store, err := limiter.OpenFileClientStore(cfg.RateLimiter.Store.Path)
+		if err != nil {
+			return nil, err
+		}
+		s.clientStore = store
+	}
+
+	return s, nil
 }
 
+// Run настраивает сервер и начинает принимать соединения
 func (s *Server) Run() error {
+	if err := s.prepare(); err != nil {
+		return err
+	}
+
+	// Управляющие маршруты обслуживаются отдельно от проксируемого трафика
+	if s.adminServer != nil {
+		slog.Info("starting admin server", slog.String("addr", s.cfg.Admin.Addr))
+		go func() {
+			if err := s.adminServer.Serve(s.adminListener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("admin server failed", slog.String("error", err.Error()))
+			}
+		}()
+	}
+
+	slog.Info("starting server", slog.String("addr", s.httpServer.Addr))
+	return s.httpServer.ListenAndServe()
+}
+
+// prepare создает HTTP-сервер, устанавливает лимиты и маршруты
+func (s *Server) prepare() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// Создаем HTTP-сервер
 	addr := fmt.Sprintf(":%d", s.cfg.Server.Port)
 	s.httpServer = &http.Server{
 		Addr: addr,
 	}
 
+	// Алгоритм по умолчанию действует и для клиентов, созданных через API
+	if err := s.limiter.SetAlgorithm("global", s.cfg.RateLimiter.Algorithm); err != nil {
+		return err
+	}
+
 	// Если включен rate limiting, устанавливаем лимиты
 	if s.cfg.RateLimiter.Enabled {
 		// Устанавливаем глобальный лимит
@@ -55,6 +145,9 @@ func (s *Server) Run() error {
 
 		// Устанавливаем индивидуальные лимиты для клиентов
 		for clientID, limit := range s.cfg.RateLimiter.Clients {
+			if err = s.limiter.SetAlgorithm(clientID, limit.Algorithm); err != nil {
+				return fmt.Errorf("failed to set client algorithm for %s: %w", clientID, err)
+			}
 			err = s.limiter.SetClientLimit(clientID, limit.RateLimit, limit.Period)
 			if err != nil {
 				return fmt.Errorf("failed to set client limit for %s: %w", clientID, err)
@@ -62,11 +155,37 @@ func (s *Server) Run() error {
 		}
 	}
 
+	// Сохраненные изменения из API применяются поверх лимитов из конфигурации
+	if err := s.applyStoredClients(); err != nil {
+		return err
+	}
+
+	// Открываем admin listener до начала приема трафика, чтобы ошибки адреса или TLS прервали запуск
+	if s.cfg.Admin.Addr != "" {
+		auditLogger, auditLog, err := openAuditLog(s.cfg.Admin.AuditLog)
+		if err != nil {
+			return err
+		}
+
+		ln, err := listenAdmin(s.cfg.Admin)
+		if err != nil {
+			_ = auditLog.Close()
+			return err
+		}
+
+		s.adminServer = &http.Server{}
+		s.adminListener = ln
+		s.auditLog = auditLog
+		s.adminAuth = handler.NewAdminAuthMiddleware(s.cfg.Admin.Token)
+		s.setupAdminRoutes(auditLogger)
+	} else {
+		slog.Warn("admin.addr is not set: client and backend management, queue state and metrics are not served")
+	}
+
 	// Настраиваем маршруты
 	s.setupRoutes()
 
-	slog.Info("starting server", slog.String("addr", addr))
-	return s.httpServer.ListenAndServe()
+	return nil
 }
 
 // Shutdown выполняет корректное завершение работы сервера
@@ -79,5 +198,71 @@ func (s *Server) Shutdown() error {
 		return fmt.Errorf("error shutting down server: %w", err)
 	}
 
+	// Останавливаем admin-сервер и закрываем журнал аудита
+	if s.adminServer != nil {
+		if err := s.adminServer.Shutdown(ctx); err != nil {
+			return fmt.Errorf("error shutting down admin server: %w", err)
+		}
+		_ = s.auditLog.Close()
+	}
+
+	if s.redisLimiter != nil {
+		_ = s.redisLimiter.Close()
+	}
+
+	if s.clientStore != nil {
+		if err := s.clientStore.Close(); err != nil {
+			return fmt.Errorf("error closing client store: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// applyStoredClients применяет клиентов из хранилища. Сохраненная запись имеет приоритет
+// над rate_limiter.clients: лимит, измененный или удаленный через API, остается таким после перезапуска
+func (s *Server) applyStoredClients() error {
+	if s.clientStore == nil {
+		return nil
+	}
+
+	records := s.clientStore.Load()
+	for _, r := range records {
+		if r.Deleted {
+			s.limiter.RemoveClientLimit(r.ClientID)
+			continue
+		}
+		if err := s.limiter.SetClientLimit(r.ClientID, r.Rate, r.Period); err != nil {
+			return fmt.Errorf("failed to set stored client limit for %s: %w", r.ClientID, err)
+		}
+	}
+
+	slog.Info("stored clients loaded", slog.Int("count", len(records)))
+	return nil
+}
+
+// isStoredClient проверяет, переопределен ли клиент записью в хранилище
+func (s *Server) isStoredClient(clientID string) bool {
+	if s.clientStore == nil {
+		return false
+	}
+	_, ok := s.clientStore.Get(clientID)
+	return ok
+}
+
+// DrainBackends выводит все бэкенды из ротации и ожидает завершения активных соединений
+// (включая WebSocket-туннели, которые не отслеживаются http.Server.Shutdown) не дольше timeout
+func (s *Server) DrainBackends(timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultDrainTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := balancerDomain.DrainBackends(ctx, s.balancer.GetBackends()); err != nil {
+		return fmt.Errorf("error draining backends: %w", err)
+	}
+
 	return nil
 }
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"CloudCamp/internal/config"
 	"CloudCamp/internal/domain/balancerDomain"
 	"CloudCamp/internal/limiter"
 )
@@ -10,7 +11,19 @@ func (s *Server) GetLimiter() *limiter.MemoryRateLimiter {
 	return s.limiter
 }
 
+// GetBalancer возвращает стратегию балансировки
+func (s *Server) GetBalancer() balancerDomain.Strategy {
+	return s.balancer
+}
+
 // GetBackends возвращает список бэкендов
 func (s *Server) GetBackends() []*balancerDomain.Backend {
 	return s.balancer.GetBackends()
 }
+
+// GetConfig возвращает текущую применённую конфигурацию
+func (s *Server) GetConfig() *config.Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg
+}
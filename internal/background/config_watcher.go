@@ -0,0 +1,109 @@
+package background
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ConfigWatcher перезагружает конфигурацию по сигналу SIGHUP или при изменении файла.
+// Если путь к файлу не задан (конфигурация из переменных окружения и флагов), файл не опрашивается
+type ConfigWatcher struct {
+	path     string
+	interval atomic.Int64 // интервал опроса файла в наносекундах (0 — опрос выключен)
+	reload   func() error
+	signals  chan os.Signal
+	modTime  time.Time
+	size     int64
+	wg       sync.WaitGroup
+}
+
+// NewConfigWatcher создает новый ConfigWatcher
+func NewConfigWatcher(path string, interval time.Duration, reload func() error) *ConfigWatcher {
+	cw := &ConfigWatcher{
+		path:    path,
+		reload:  reload,
+		signals: make(chan os.Signal, 1),
+	}
+	cw.SetInterval(interval)
+	cw.changed() // запоминаем исходное состояние файла
+
+	return cw
+}
+
+// Start запускает отслеживание сигналов и изменений файла
+func (cw *ConfigWatcher) Start(ctx context.Context) {
+	signal.Notify(cw.signals, syscall.SIGHUP)
+
+	cw.wg.Add(1)
+	go func() {
+		defer cw.wg.Done()
+		defer signal.Stop(cw.signals)
+
+		for {
+			// При выключенном опросе или без файла канал остается nil и ожидаются только сигналы
+			var poll <-chan time.Time
+			if interval := time.Duration(cw.interval.Load()); interval > 0 && cw.path != "" {
+				poll = time.After(interval)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-cw.signals:
+				slog.Info("SIGHUP received, reloading config", "path", cw.path)
+				cw.changed()
+				cw.apply()
+			case <-poll:
+				if cw.changed() {
+					slog.Info("Config file changed, reloading config", "path", cw.path)
+					cw.apply()
+				}
+			}
+		}
+	}()
+}
+
+// Wait ожидает завершения работы
+func (cw *ConfigWatcher) Wait() {
+	cw.wg.Wait()
+}
+
+// SetInterval изменяет интервал опроса файла во время работы
+func (cw *ConfigWatcher) SetInterval(interval time.Duration) {
+	cw.interval.Store(int64(interval))
+}
+
+// apply выполняет перезагрузку. При ошибке продолжает работать прежняя конфигурация
+func (cw *ConfigWatcher) apply() {
+	if err := cw.reload(); err != nil {
+		slog.Error("Config reload failed, keeping current config", "error", err)
+		return
+	}
+	slog.Info("Config reloaded", "path", cw.path)
+}
+
+// changed проверяет, изменились ли время модификации или размер файла с прошлой проверки
+func (cw *ConfigWatcher) changed() bool {
+	if cw.path == "" {
+		return false
+	}
+
+	info, err := os.Stat(cw.path)
+	if err != nil {
+		slog.Warn("Failed to stat config file", "path", cw.path, "error", err)
+		return false
+	}
+
+	if info.ModTime().Equal(cw.modTime) && info.Size() == cw.size {
+		return false
+	}
+	cw.modTime = info.ModTime()
+	cw.size = info.Size()
+	return true
+}
@@ -2,6 +2,7 @@ package background
 
 import (
 	"CloudCamp/internal/domain/balancerDomain"
+	"CloudCamp/internal/metrics"
 	"context"
 	"log/slog"
 	"net/http"
@@ -9,21 +10,27 @@ import (
 	"time"
 )
 
+// BackendSource источник актуального списка бэкендов
+type BackendSource interface {
+	GetBackends() []*balancerDomain.Backend
+}
+
 // HealthChecker периодически проверяет доступность бэкендов
 type HealthChecker struct {
-	backends []*balancerDomain.Backend
-	ticker   *time.Ticker
-	client   *http.Client
-	path     string
-	wg       sync.WaitGroup
+	source BackendSource // список бэкендов запрашивается на каждой проверке, чтобы учитывать изменения пула
+	ticker *time.Ticker
+	client *http.Client
+	mu     sync.RWMutex
+	path   string
+	wg     sync.WaitGroup
 }
 
 // NewHealthChecker создает новый HealthChecker
-func NewHealthChecker(backends []*balancerDomain.Backend, interval time.Duration, path string) *HealthChecker {
+func NewHealthChecker(source BackendSource, interval time.Duration, path string) *HealthChecker {
 	return &HealthChecker{
-		backends: backends,
-		ticker:   time.NewTicker(interval),
-		path:     path,
+		source: source,
+		ticker: time.NewTicker(interval),
+		path:   path,
 		client: &http.Client{
 			Timeout: 5 * time.Second,
 		},
@@ -53,24 +60,63 @@ func (hc *HealthChecker) Wait() {
 	hc.wg.Wait()
 }
 
-// checkBackends проверяет доступность всех бэкендов
+// SetInterval изменяет интервал проверок во время работы
+func (hc *HealthChecker) SetInterval(interval time.Duration) {
+	if interval > 0 {
+		hc.ticker.Reset(interval)
+	}
+}
+
+// SetPath изменяет путь проверки здоровья во время работы
+func (hc *HealthChecker) SetPath(path string) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	hc.path = path
+}
+
+// getPath возвращает текущий путь проверки здоровья
+func (hc *HealthChecker) getPath() string {
+	hc.mu.RLock()
+	defer hc.mu.RUnlock()
+	return hc.path
+}
+
+// checkBackends проверяет доступность всех бэкендов.
+// Результаты проверок учитываются вместе с ошибками живого трафика через пороги rise/fall
 func (hc *HealthChecker) checkBackends() {
-	for _, backend := range hc.backends {
+	for _, backend := range hc.source.GetBackends() {
 		go func(b *balancerDomain.Backend) {
-			resp, err := hc.client.Get(b.URL + hc.path)
-			if err != nil {
-				slog.Warn("Backend is unavailable", "backend", b.URL+hc.path)
-				b.SetAlive(false)
+			start := time.Now()
+			healthy := hc.probe(b)
+			metrics.ObserveHealthCheck(b.ID, healthy, time.Since(start))
+
+			if healthy {
+				if b.ReportSuccess() {
+					slog.Info("Backend is back online", "backend", b.URL)
+				}
 				return
 			}
-			defer resp.Body.Close()
 
-			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-				b.SetAlive(true)
-			} else {
-				slog.Warn("Backend returned non-2xx status", "backend", b.URL, "status", resp.StatusCode)
-				b.SetAlive(false)
+			if b.ReportFailure() {
+				slog.Warn("Backend marked down", "backend", b.URL)
 			}
 		}(backend)
 	}
 }
+
+// probe выполняет одну проверку бэкенда и возвращает true, если он ответил 2xx
+func (hc *HealthChecker) probe(b *balancerDomain.Backend) bool {
+	path := hc.getPath()
+	resp, err := hc.client.Get(b.URL + path)
+	if err != nil {
+		slog.Warn("Backend is unavailable", "backend", b.URL+path)
+		return false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		slog.Warn("Backend returned non-2xx status", "backend", b.URL, "status", resp.StatusCode)
+		return false
+	}
+	return true
+}
@@ -46,6 +46,13 @@ func (tr *TokenRefill) Wait() {
 	tr.wg.Wait()
 }
 
+// SetInterval изменяет интервал пополнения токенов во время работы
+func (tr *TokenRefill) SetInterval(interval time.Duration) {
+	if interval > 0 {
+		tr.ticker.Reset(interval)
+	}
+}
+
 // refillTokens пополняет токены во всех бакетах
 func (tr *TokenRefill) refillTokens() {
 	tr.limiter.RefillAll()
@@ -0,0 +1,134 @@
+package balancer
+
+import (
+	"CloudCamp/internal/domain/balancerDomain"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// defaultVirtualNodes количество виртуальных нод на единицу веса по умолчанию
+const defaultVirtualNodes = 160
+
+// ringNode виртуальная нода на кольце хешей
+type ringNode struct {
+	hash    uint64
+	backend *balancerDomain.Backend
+}
+
+// ConsistentHashBalancer направляет запросы с одинаковым ключом на один и тот же бэкенд.
+// Кольцо строится по всем бэкендам, а недоступные пропускаются при поиске,
+// поэтому при падении бэкенда переезжают только его ключи
+type ConsistentHashBalancer struct {
+	*balancerDomain.BaseBalancer
+	keyFunc      KeyFunc
+	virtualNodes int
+	mu           sync.RWMutex
+	ring         []ringNode // виртуальные ноды, отсортированные по хешу
+}
+
+// NewConsistentHashBalancer создает новый балансировщик с алгоритмом Consistent Hashing
+func NewConsistentHashBalancer(backends []*balancerDomain.Backend, keyFunc KeyFunc, virtualNodes int) *ConsistentHashBalancer {
+	if virtualNodes <= 0 {
+		virtualNodes = defaultVirtualNodes
+	}
+
+	c := &ConsistentHashBalancer{
+		BaseBalancer: balancerDomain.NewBaseBalancer(backends),
+		keyFunc:      keyFunc,
+		virtualNodes: virtualNodes,
+	}
+	c.buildRing(backends)
+
+	return c
+}
+
+// NextBackend выбирает бэкенд для пустого ключа (используется, когда запрос неизвестен)
+func (c *ConsistentHashBalancer) NextBackend() *balancerDomain.Backend {
+	return c.NextBackendByKey("")
+}
+
+// SelectBackend выбирает бэкенд по ключу из запроса.
+// Если ключ в запросе отсутствует, используется IP-адрес клиента
+func (c *ConsistentHashBalancer) SelectBackend(rc *balancerDomain.RoutingContext) *balancerDomain.Backend {
+	key := c.keyFunc(rc)
+	if key == "" {
+		key = rc.ClientIP
+	}
+
+	return c.selectByKey(key, rc)
+}
+
+// NextBackendByKey находит первую доступную виртуальную ноду по часовой стрелке от хеша ключа
+func (c *ConsistentHashBalancer) NextBackendByKey(key string) *balancerDomain.Backend {
+	return c.selectByKey(key, nil)
+}
+
+// selectByKey обходит кольцо от хеша ключа, пропуская недоступные и исключенные бэкенды
+func (c *ConsistentHashBalancer) selectByKey(key string, rc *balancerDomain.RoutingContext) *balancerDomain.Backend {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(c.ring) == 0 {
+		return nil
+	}
+
+	h := hashKey(key)
+	idx := sort.Search(len(c.ring), func(i int) bool {
+		return c.ring[i].hash >= h
+	})
+
+	for i := 0; i < len(c.ring); i++ {
+		node := c.ring[(idx+i)%len(c.ring)]
+		if node.backend.IsAvailable() && !rc.IsExcluded(node.backend) {
+			return node.backend
+		}
+	}
+
+	return nil
+}
+
+// MarkBackendDown помечает бэкенд как недоступный
+func (c *ConsistentHashBalancer) MarkBackendDown(backend *balancerDomain.Backend) {
+	c.BaseBalancer.MarkBackendDown(backend)
+}
+
+// MarkBackendUp помечает бэкенд как доступный
+func (c *ConsistentHashBalancer) MarkBackendUp(backend *balancerDomain.Backend) {
+	c.BaseBalancer.MarkBackendUp(backend)
+}
+
+// UpdateBackends обновляет список бэкендов и перестраивает кольцо
+func (c *ConsistentHashBalancer) UpdateBackends(backends []*balancerDomain.Backend) {
+	c.BaseBalancer.UpdateBackends(backends)
+	c.buildRing(backends)
+}
+
+// buildRing строит кольцо: каждому бэкенду выделяется virtualNodes*Weight виртуальных нод
+func (c *ConsistentHashBalancer) buildRing(backends []*balancerDomain.Backend) {
+	var ring []ringNode
+	for _, b := range backends {
+		for i := 0; i < c.virtualNodes*b.Weight; i++ {
+			ring = append(ring, ringNode{
+				hash:    hashKey(b.URL + "#" + strconv.Itoa(i)),
+				backend: b,
+			})
+		}
+	}
+
+	sort.Slice(ring, func(i, j int) bool {
+		return ring[i].hash < ring[j].hash
+	})
+
+	c.mu.Lock()
+	c.ring = ring
+	c.mu.Unlock()
+}
+
+// hashKey вычисляет 64-битный FNV-1a хеш строки
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum64()
+}
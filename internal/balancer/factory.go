@@ -3,25 +3,102 @@ package balancer
 import (
 	"CloudCamp/internal/config"
 	"CloudCamp/internal/domain/balancerDomain"
+	"fmt"
 )
 
 // NewBalancerFactory создает новый экземпляр балансировщика с указанной стратегией
-func NewBalancerFactory(cfg *config.Config) balancerDomain.Strategy {
+func NewBalancerFactory(cfg *config.Config) (balancerDomain.Strategy, error) {
 
 	var backends []*balancerDomain.Backend
 
-	for _, url := range cfg.Balancer.Backends {
-		backends = append(backends, balancerDomain.NewBackend(url))
+	for _, b := range cfg.Balancer.Backends {
+		backends = append(backends, NewBackendFromConfig(cfg, b))
 	}
 
+	return NewStrategy(cfg, backends)
+}
+
+// NewStrategy создает стратегию из конфигурации для уже созданных бэкендов
+func NewStrategy(cfg *config.Config, backends []*balancerDomain.Backend) (balancerDomain.Strategy, error) {
 	switch cfg.Balancer.Strategy {
 	case "round-robin":
-		return NewRoundRobinBalancer(backends)
+		return NewRoundRobinBalancer(backends), nil
+	case "weighted-round-robin":
+		return NewWeightedRoundRobinBalancer(backends), nil
 	case "random":
-		return NewRandomBalancer(backends)
+		return NewRandomBalancer(backends), nil
 	case "least-connections":
-		return NewLeastConnectionsBalancer(backends)
+		return NewLeastConnectionsBalancer(backends), nil
+	case "consistent-hash":
+		keyFunc, err := NewKeyFunc(cfg.Balancer.ConsistentHash.KeySource, cfg.Balancer.ConsistentHash.KeyName)
+		if err != nil {
+			return nil, fmt.Errorf("invalid consistent hash key: %w", err)
+		}
+		return NewConsistentHashBalancer(backends, keyFunc, cfg.Balancer.ConsistentHash.VirtualNodes), nil
 	default:
+		return nil, fmt.Errorf("unsupported balancing strategy: %s", cfg.Balancer.Strategy)
+	}
+}
+
+// NewBackendFromConfig создает бэкенд с порогами здоровья и circuit breaker из конфигурации
+func NewBackendFromConfig(cfg *config.Config, bc config.BackendConfig) *balancerDomain.Backend {
+	backend := balancerDomain.NewBackend(bc.URL, bc.Weight)
+	if bc.ID != "" {
+		backend.ID = bc.ID
+	}
+	ApplyBackendSettings(cfg, backend)
+
+	return backend
+}
+
+// ApplyBackendSettings применяет к бэкенду пороги здоровья, лимиты одновременных запросов
+// и настройки circuit breaker из конфигурации
+func ApplyBackendSettings(cfg *config.Config, backend *balancerDomain.Backend) {
+	backend.SetHealthThresholds(cfg.HealthChecker.Rise, cfg.HealthChecker.Fall)
+
+	maxConnections := 0
+	if cfg.Concurrency.Enabled {
+		maxConnections = cfg.Concurrency.PerBackend
+	}
+	backend.SetMaxConnections(maxConnections)
+	backend.SetAdaptiveLimit(NewAdaptiveLimit(cfg.Concurrency))
+
+	cb := cfg.Balancer.CircuitBreaker
+	if !cb.Enabled {
+		backend.SetCircuitBreaker(nil)
+		return
+	}
+
+	backend.SetCircuitBreaker(balancerDomain.NewCircuitBreaker(balancerDomain.CircuitBreakerSettings{
+		Window:           cb.Window,
+		MinRequests:      cb.MinRequests,
+		ErrorRate:        cb.ErrorRate,
+		SlowThreshold:    cb.SlowThreshold,
+		SlowRate:         cb.SlowRate,
+		Cooldown:         cb.Cooldown,
+		HalfOpenRequests: cb.HalfOpenRequests,
+	}))
+}
+
+// NewAdaptiveLimit создает адаптивный лимит бэкенда из конфигурации или возвращает nil, если он выключен
+func NewAdaptiveLimit(cfg config.ConcurrencyConfig) *balancerDomain.AdaptiveLimit {
+	a := cfg.Adaptive
+	if !cfg.Enabled || !a.Enabled {
 		return nil
 	}
+
+	algorithm := balancerDomain.LimitAIMD
+	if a.Algorithm == config.AdaptiveGradient {
+		algorithm = balancerDomain.LimitGradient
+	}
+
+	return balancerDomain.NewAdaptiveLimit(balancerDomain.AdaptiveLimitSettings{
+		Algorithm:        algorithm,
+		InitialLimit:     a.InitialLimit,
+		MinLimit:         a.MinLimit,
+		MaxLimit:         a.MaxLimit,
+		LatencyThreshold: a.LatencyThreshold,
+		BackoffRatio:     a.BackoffRatio,
+		Tolerance:        a.Tolerance,
+	})
 }
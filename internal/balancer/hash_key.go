@@ -0,0 +1,51 @@
+package balancer
+
+import (
+	"CloudCamp/internal/domain/balancerDomain"
+	"fmt"
+)
+
+// Источники ключа для consistent hashing
+const (
+	KeySourceClientID = "client-id" // заголовок X-Client-ID
+	KeySourceHeader   = "header"    // произвольный заголовок
+	KeySourceCookie   = "cookie"    // значение cookie
+	KeySourceIP       = "ip"        // IP-адрес клиента
+)
+
+// KeyFunc извлекает из контекста маршрутизации ключ, по которому выбирается бэкенд
+type KeyFunc func(rc *balancerDomain.RoutingContext) string
+
+// NewKeyFunc создает функцию извлечения ключа для указанного источника
+func NewKeyFunc(source, name string) (KeyFunc, error) {
+	switch source {
+	case KeySourceClientID, "":
+		return func(rc *balancerDomain.RoutingContext) string {
+			return rc.Request.Header.Get("X-Client-ID")
+		}, nil
+	case KeySourceHeader:
+		if name == "" {
+			return nil, fmt.Errorf("key source %q requires key name", source)
+		}
+		return func(rc *balancerDomain.RoutingContext) string {
+			return rc.Request.Header.Get(name)
+		}, nil
+	case KeySourceCookie:
+		if name == "" {
+			return nil, fmt.Errorf("key source %q requires key name", source)
+		}
+		return func(rc *balancerDomain.RoutingContext) string {
+			c, err := rc.Request.Cookie(name)
+			if err != nil {
+				return ""
+			}
+			return c.Value
+		}, nil
+	case KeySourceIP:
+		return func(rc *balancerDomain.RoutingContext) string {
+			return rc.ClientIP
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown key source: %s", source)
+	}
+}
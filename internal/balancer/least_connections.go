@@ -15,7 +15,12 @@ func NewLeastConnectionsBalancer(backends []*balancerDomain.Backend) *LeastConne
 
 // NextBackend выбирает бэкенд с наименьшим числом активных соединений
 func (l *LeastConnectionsBalancer) NextBackend() *balancerDomain.Backend {
-	available := l.GetAvailableBackends()
+	return l.SelectBackend(nil)
+}
+
+// SelectBackend выбирает неисключенный бэкенд с наименьшим числом активных соединений
+func (l *LeastConnectionsBalancer) SelectBackend(rc *balancerDomain.RoutingContext) *balancerDomain.Backend {
+	available := rc.Allowed(l.GetAvailableBackends())
 	if len(available) == 0 {
 		return nil
 	}
@@ -21,7 +21,12 @@ func NewRandomBalancer(backends []*balancerDomain.Backend) *RandomBalancer {
 
 // NextBackend выбирает случайный доступный бэкенд
 func (r *RandomBalancer) NextBackend() *balancerDomain.Backend {
-	available := r.GetAvailableBackends()
+	return r.SelectBackend(nil)
+}
+
+// SelectBackend выбирает случайный бэкенд среди неисключенных
+func (r *RandomBalancer) SelectBackend(rc *balancerDomain.RoutingContext) *balancerDomain.Backend {
+	available := rc.Allowed(r.GetAvailableBackends())
 	if len(available) == 0 {
 		return nil
 	}
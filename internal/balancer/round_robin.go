@@ -19,19 +19,25 @@ func NewRoundRobinBalancer(backends []*balancerDomain.Backend) *RoundRobinBalanc
 
 // NextBackend возвращает следующий доступный бэкенд
 func (r *RoundRobinBalancer) NextBackend() *balancerDomain.Backend {
+	return r.SelectBackend(nil)
+}
+
+// SelectBackend возвращает следующий доступный бэкенд, пропуская исключенные.
+// Очередь сдвигается на выбранный бэкенд, поэтому повторная попытка занимает одну позицию, как обычный запрос
+func (r *RoundRobinBalancer) SelectBackend(rc *balancerDomain.RoutingContext) *balancerDomain.Backend {
 	available := r.GetAvailableBackends()
-	if len(available) == 0 {
-		return nil
-	}
+	n := uint64(len(available))
 
-	// Атомарно получаем текущее значение и инкрементируем его
 	current := r.current.Load()
-	next := (current + 1) % uint64(len(available))
-
-	// Устанавливаем новое значение
-	r.current.Store(next)
-
-	return available[next]
+	for i := uint64(1); i <= n; i++ {
+		next := (current + i) % n
+		if !rc.IsExcluded(available[next]) {
+			// Устанавливаем новое значение
+			r.current.Store(next)
+			return available[next]
+		}
+	}
+	return nil
 }
 
 // MarkBackendDown помечает бэкенд как недоступный
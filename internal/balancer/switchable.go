@@ -0,0 +1,89 @@
+package balancer
+
+import (
+	"CloudCamp/internal/domain/balancerDomain"
+	"sync"
+	"sync/atomic"
+)
+
+// SwitchableBalancer делегирует выбор бэкенда текущей стратегии и позволяет
+// заменить стратегию во время работы, не прерывая обработку запросов
+type SwitchableBalancer struct {
+	mu      sync.Mutex // сериализует изменения пула и замену стратегии
+	current atomic.Pointer[balancerDomain.RequestStrategy]
+}
+
+// NewSwitchableBalancer создает балансировщик с возможностью замены стратегии
+func NewSwitchableBalancer(strategy balancerDomain.Strategy) *SwitchableBalancer {
+	s := &SwitchableBalancer{}
+	s.store(strategy)
+	return s
+}
+
+// Current возвращает текущую стратегию
+func (s *SwitchableBalancer) Current() balancerDomain.RequestStrategy {
+	return *s.current.Load()
+}
+
+// Swap заменяет стратегию. Новая стратегия строится по текущему пулу бэкендов,
+// поэтому счетчики соединений и состояние бэкендов сохраняются. Если стратегию построить не удалось,
+// продолжает работать текущая
+func (s *SwitchableBalancer) Swap(build func(backends []*balancerDomain.Backend) (balancerDomain.Strategy, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	strategy, err := build(s.Current().GetBackends())
+	if err != nil {
+		return err
+	}
+	s.store(strategy)
+	return nil
+}
+
+// ModifyBackends атомарно изменяет пул бэкендов: fn получает текущий список и возвращает новый
+func (s *SwitchableBalancer) ModifyBackends(fn func(current []*balancerDomain.Backend) []*balancerDomain.Backend) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := s.Current()
+	current.UpdateBackends(fn(current.GetBackends()))
+}
+
+// NextBackend возвращает следующий доступный бэкенд текущей стратегии
+func (s *SwitchableBalancer) NextBackend() *balancerDomain.Backend {
+	return s.Current().NextBackend()
+}
+
+// SelectBackend выбирает бэкенд для запроса текущей стратегией
+func (s *SwitchableBalancer) SelectBackend(rc *balancerDomain.RoutingContext) *balancerDomain.Backend {
+	return s.Current().SelectBackend(rc)
+}
+
+// MarkBackendDown помечает бэкенд как недоступный
+func (s *SwitchableBalancer) MarkBackendDown(backend *balancerDomain.Backend) {
+	s.Current().MarkBackendDown(backend)
+}
+
+// MarkBackendUp помечает бэкенд как доступный
+func (s *SwitchableBalancer) MarkBackendUp(backend *balancerDomain.Backend) {
+	s.Current().MarkBackendUp(backend)
+}
+
+// UpdateBackends обновляет список бэкендов текущей стратегии
+func (s *SwitchableBalancer) UpdateBackends(backends []*balancerDomain.Backend) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Current().UpdateBackends(backends)
+}
+
+// GetBackends возвращает список всех бэкендов
+func (s *SwitchableBalancer) GetBackends() []*balancerDomain.Backend {
+	return s.Current().GetBackends()
+}
+
+// store сохраняет стратегию, приводя ее к RequestStrategy
+func (s *SwitchableBalancer) store(strategy balancerDomain.Strategy) {
+	rs := balancerDomain.AsRequestStrategy(strategy)
+	s.current.Store(&rs)
+}
@@ -0,0 +1,76 @@
+package balancer
+
+import (
+	"CloudCamp/internal/domain/balancerDomain"
+	"sync"
+)
+
+// WeightedRoundRobinBalancer реализует плавный взвешенный Round Robin (как в nginx):
+// бэкенды чередуются пропорционально весам, без пачек подряд на один сервер
+type WeightedRoundRobinBalancer struct {
+	*balancerDomain.BaseBalancer
+	mu      sync.Mutex
+	current map[*balancerDomain.Backend]int // текущие веса бэкендов
+}
+
+// NewWeightedRoundRobinBalancer создает новый балансировщик с алгоритмом Smooth Weighted Round Robin
+func NewWeightedRoundRobinBalancer(backends []*balancerDomain.Backend) *WeightedRoundRobinBalancer {
+	return &WeightedRoundRobinBalancer{
+		BaseBalancer: balancerDomain.NewBaseBalancer(backends),
+		current:      make(map[*balancerDomain.Backend]int),
+	}
+}
+
+// NextBackend возвращает бэкенд с наибольшим текущим весом
+func (w *WeightedRoundRobinBalancer) NextBackend() *balancerDomain.Backend {
+	return w.SelectBackend(nil)
+}
+
+// SelectBackend возвращает бэкенд с наибольшим текущим весом среди неисключенных.
+// На каждом шаге текущий вес всех кандидатов увеличивается на их эффективный вес,
+// а у выбранного уменьшается на сумму весов. Исключенные бэкенды в шаге не участвуют,
+// поэтому повтор запроса не сдвигает их очередь
+func (w *WeightedRoundRobinBalancer) SelectBackend(rc *balancerDomain.RoutingContext) *balancerDomain.Backend {
+	available := rc.Allowed(w.GetAvailableBackends())
+	if len(available) == 0 {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var selected *balancerDomain.Backend
+	total := 0
+
+	for _, b := range available {
+		w.current[b] += b.Weight
+		total += b.Weight
+
+		if selected == nil || w.current[b] > w.current[selected] {
+			selected = b
+		}
+	}
+
+	w.current[selected] -= total
+
+	return selected
+}
+
+// MarkBackendDown помечает бэкенд как недоступный
+func (w *WeightedRoundRobinBalancer) MarkBackendDown(backend *balancerDomain.Backend) {
+	w.BaseBalancer.MarkBackendDown(backend)
+}
+
+// MarkBackendUp помечает бэкенд как доступный
+func (w *WeightedRoundRobinBalancer) MarkBackendUp(backend *balancerDomain.Backend) {
+	w.BaseBalancer.MarkBackendUp(backend)
+}
+
+// UpdateBackends обновляет список бэкендов и сбрасывает накопленные текущие веса
+func (w *WeightedRoundRobinBalancer) UpdateBackends(backends []*balancerDomain.Backend) {
+	w.mu.Lock()
+	w.current = make(map[*balancerDomain.Backend]int)
+	w.mu.Unlock()
+
+	w.BaseBalancer.UpdateBackends(backends)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"gopkg.in/yaml.v3"
 	"os"
+	"reflect"
+	"strings"
 	"time"
 )
 
@@ -18,15 +20,47 @@ const (
 type Config struct {
 	Env           Environment         `yaml:"env"`
 	Server        ServerConfig        `yaml:"server"`
+	Admin         AdminConfig         `yaml:"admin"`
 	Balancer      BalancerConfig      `yaml:"balancer"`
+	Proxy         ProxyConfig         `yaml:"proxy"`
 	RateLimiter   RateLimitConfig     `yaml:"rate_limiter"`
+	Concurrency   ConcurrencyConfig   `yaml:"concurrency"`
 	HealthChecker HealthCheckerConfig `yaml:"health_checker"`
 	Log           LogConfig           `yaml:"log"`
+	Reload        ReloadConfig        `yaml:"reload"`
 }
 
 // ServerConfig — содержит настройки сервера
 type ServerConfig struct {
-	Port int `yaml:"port"`
+	Port            int           `yaml:"port"`
+	DrainTimeout    time.Duration `yaml:"drain_timeout"`    // Время ожидания завершения активных соединений с бэкендами при остановке
+	TrustedProxies  []string      `yaml:"trusted_proxies"`  // Доверенные прокси (CIDR или адреса), чьему заголовку с адресом клиента можно верить
+	ForwardedHeader string        `yaml:"forwarded_header"` // Заголовок с адресом клиента от доверенных прокси: xff, forwarded или x-real-ip
+}
+
+// AdminConfig содержит настройки отдельного listener для управляющих маршрутов (/clients, /admin/*, /metrics)
+type AdminConfig struct {
+	Addr     string         `yaml:"addr"`      // Адрес host:port или unix-сокет в виде unix:/path/admin.sock (пусто — управляющие маршруты отключены)
+	Token    string         `yaml:"token"`     // Bearer-токен, обязательный для каждого запроса
+	TLS      AdminTLSConfig `yaml:"tls"`       // TLS и проверка клиентских сертификатов (mTLS)
+	AuditLog string         `yaml:"audit_log"` // Файл журнала аудита изменений (пусто — общий лог приложения)
+}
+
+// AdminTLSConfig содержит настройки TLS для admin listener
+type AdminTLSConfig struct {
+	CertFile     string `yaml:"cert_file"`      // Сертификат сервера
+	KeyFile      string `yaml:"key_file"`       // Закрытый ключ сервера
+	ClientCAFile string `yaml:"client_ca_file"` // CA для проверки клиентских сертификатов (включает mTLS)
+}
+
+// IsUnix проверяет, задан ли адрес admin listener unix-сокетом
+func (c AdminConfig) IsUnix() bool {
+	return strings.HasPrefix(c.Addr, "unix:")
+}
+
+// SocketPath возвращает путь к unix-сокету admin listener
+func (c AdminConfig) SocketPath() string {
+	return strings.TrimPrefix(c.Addr, "unix:")
 }
 
 // ClientLimit содержит настройки лимита для конкретного клиента
@@ -34,15 +68,169 @@ type ClientLimit struct {
 	ClientID  int           `yaml:"client_id"` // Уникальный идентификатор клиента
 	RateLimit int           `yaml:"rate"`      // Персональный лимит: максимальное количество запросов, которые клиент может сделать за период
 	Period    time.Duration `yaml:"period"`    // Персональный период (в секундах), с учётом которого будут добавляться токены для клиента
+	Algorithm string        `yaml:"algorithm"` // Алгоритм лимита клиента (пусто — глобальный алгоритм)
 }
 
 // RateLimitConfig конфигурация для rate limiter
 type RateLimitConfig struct {
-	Enabled  bool                   `yaml:"enabled"`  // Включение или выключение rate limiter
-	Interval time.Duration          `yaml:"interval"` // Интервал времени для глобального лимита
-	Rate     int                    `yaml:"rate"`     // Глобальный лимит: максимальное количество запросов для всех клиентов за глобальный период
-	Period   time.Duration          `yaml:"period"`   // Глобальный период, в течение которого обновляются токены для всех клиентов
-	Clients  map[string]ClientLimit `yaml:"clients"`  // Карта индивидуальных лимитов для каждого клиента (ключ — ID клиента)
+	Enabled   bool                   `yaml:"enabled"`   // Включение или выключение rate limiter
+	Interval  time.Duration          `yaml:"interval"`  // Интервал времени для глобального лимита
+	Rate      int                    `yaml:"rate"`      // Глобальный лимит: максимальное количество запросов для всех клиентов за глобальный период
+	Period    time.Duration          `yaml:"period"`    // Глобальный период, в течение которого обновляются токены для всех клиентов
+	Algorithm string                 `yaml:"algorithm"` // Алгоритм по умолчанию для глобального лимита и клиентов
+	Clients   map[string]ClientLimit `yaml:"clients"`   // Карта индивидуальных лимитов для каждого клиента (ключ — ID клиента)
+	Store     ClientStoreConfig      `yaml:"store"`     // Хранилище клиентов, созданных и измененных через API
+	Backend   string                 `yaml:"backend"`   // Где хранятся бакеты: memory (в процессе) или redis (общие для всех реплик)
+	Redis     RedisConfig            `yaml:"redis"`     // Настройки подключения к Redis (для backend: redis)
+	Auth      RateLimitAuthConfig    `yaml:"auth"`      // Аутентификация клиентов для политик (API-ключи и JWT)
+	Policies  []RateLimitPolicy      `yaml:"policies"`  // Политики лимитов, применяемые дополнительно к глобальному и клиентским лимитам
+}
+
+// Источники ключа, по которому политика считает запросы
+const (
+	PolicyKeyIP         = "ip"          // IP-адрес клиента
+	PolicyKeyClientID   = "client-id"   // заголовок X-Client-ID, при его отсутствии — IP-адрес
+	PolicyKeyAPIKey     = "api-key"     // клиент, аутентифицированный по API-ключу
+	PolicyKeyJWTSubject = "jwt-subject" // subject проверенного JWT
+	PolicyKeyRoute      = "route"       // один общий счетчик на все запросы, подходящие под политику
+	PolicyKeyHeader     = "header:"     // значение заголовка, например header:X-Tenant-ID
+)
+
+// RateLimitPolicy политика лимита: условия применения, ключ счетчика и лимит.
+// Если к запросу подходят несколько политик, учитываются все, и решение определяет самая строгая
+type RateLimitPolicy struct {
+	Name      string        `yaml:"name"`      // Уникальное имя политики (используется в ключах счетчиков)
+	Match     PolicyMatch   `yaml:"match"`     // Условия применения; пустые условия подходят для любого запроса
+	Key       string        `yaml:"key"`       // Источник ключа счетчика: ip, client-id, api-key, jwt-subject, route или header:<имя>
+	Rate      int           `yaml:"rate"`      // Количество запросов за период на один ключ
+	Period    time.Duration `yaml:"period"`    // Период
+	Algorithm string        `yaml:"algorithm"` // Алгоритм (пусто — rate_limiter.algorithm)
+}
+
+// PolicyMatch условия применения политики. Заданные условия должны выполняться одновременно
+type PolicyMatch struct {
+	PathPrefix  string   `yaml:"path_prefix"`  // Префикс пути запроса
+	Methods     []string `yaml:"methods"`      // HTTP-методы
+	Hosts       []string `yaml:"hosts"`        // Значения заголовка Host (без порта)
+	APIKeys     []string `yaml:"api_keys"`     // Клиенты, аутентифицированные по API-ключу ("*" — любой)
+	JWTSubjects []string `yaml:"jwt_subjects"` // Subject проверенного JWT ("*" — любой)
+}
+
+// RateLimitAuthConfig настройки аутентификации клиентов для политик лимитов.
+// Непрошедшие проверку ключи и токены считаются отсутствующими
+type RateLimitAuthConfig struct {
+	APIKeyHeader string            `yaml:"api_key_header"` // Заголовок с API-ключом
+	APIKeys      map[string]string `yaml:"api_keys"`       // Идентификатор клиента -> API-ключ
+	JWT          JWTConfig         `yaml:"jwt"`            // Проверка JWT (включена, если задан secret или public_key_file)
+}
+
+// JWTConfig настройки проверки JWT. Поддерживаются HS256 (secret) и RS256 (public_key_file)
+type JWTConfig struct {
+	Header        string `yaml:"header"`          // Заголовок с токеном в формате Bearer
+	Secret        string `yaml:"secret"`          // Секрет для HS256
+	PublicKeyFile string `yaml:"public_key_file"` // Открытый ключ RSA в PEM для RS256
+	Issuer        string `yaml:"issuer"`          // Ожидаемый iss (пусто — не проверяется)
+	Audience      string `yaml:"audience"`        // Ожидаемый aud (пусто — не проверяется)
+}
+
+// Enabled проверяет, включена ли проверка JWT
+func (c JWTConfig) Enabled() bool {
+	return c.Secret != "" || c.PublicKeyFile != ""
+}
+
+// Алгоритмы rate limiter
+const (
+	AlgorithmTokenBucket          = "token-bucket"           // токены пополняются равномерно, допускается всплеск до rate
+	AlgorithmSlidingWindowLog     = "sliding-window-log"     // точный учет времени каждого запроса в окне
+	AlgorithmSlidingWindowCounter = "sliding-window-counter" // счетчики текущего и предыдущего окна с линейной интерполяцией
+	AlgorithmGCRA                 = "gcra"                   // Generic Cell Rate Algorithm: равномерный интервал между запросами
+)
+
+// Хранилища бакетов rate limiter
+const (
+	LimiterBackendMemory = "memory"
+	LimiterBackendRedis  = "redis"
+)
+
+// Политики поведения распределенного rate limiter при недоступности Redis
+const (
+	FailPolicyOpen   = "open"   // пропускать запросы без проверки лимита
+	FailPolicyClosed = "closed" // отклонять запросы
+)
+
+// RedisConfig содержит настройки распределенного rate limiter
+type RedisConfig struct {
+	Addr       string        `yaml:"addr"`        // Адрес host:port
+	Password   string        `yaml:"password"`    // Пароль (AUTH), пусто — без аутентификации
+	DB         int           `yaml:"db"`          // Номер базы
+	KeyPrefix  string        `yaml:"key_prefix"`  // Префикс ключей бакетов
+	Timeout    time.Duration `yaml:"timeout"`     // Таймаут подключения и одного запроса
+	PoolSize   int           `yaml:"pool_size"`   // Максимум соединений с Redis
+	FailPolicy string        `yaml:"fail_policy"` // Поведение при недоступности Redis: open (пропускать) или closed (отклонять)
+}
+
+// Типы хранилища клиентов
+const (
+	ClientStoreMemory = "memory" // клиенты, созданные через API, теряются при перезапуске
+	ClientStoreFile   = "file"   // клиенты сохраняются в файл-журнал
+)
+
+// ClientStoreConfig содержит настройки хранилища клиентов, созданных через API.
+// Сохраненные записи имеют приоритет над rate_limiter.clients из конфигурации
+type ClientStoreConfig struct {
+	Type string `yaml:"type"` // memory или file
+	Path string `yaml:"path"` // Путь к файлу-журналу (для file)
+}
+
+// ConcurrencyConfig содержит настройки ограничения числа одновременных запросов.
+// В отличие от rate limiter, ограничивает не частоту, а число запросов, обрабатываемых в данный момент
+type ConcurrencyConfig struct {
+	Enabled      bool           `yaml:"enabled"`
+	PerClient    int            `yaml:"per_client"`    // Максимум одновременных запросов одного клиента (0 — без ограничения)
+	Clients      map[string]int `yaml:"clients"`       // Индивидуальные лимиты клиентов (ключ — клиент API-ключа, subject JWT или IP, 0 — без ограничения)
+	PerBackend   int            `yaml:"per_backend"`   // Максимум одновременных запросов к одному бэкенду (0 — без ограничения)
+	QueueTimeout time.Duration  `yaml:"queue_timeout"` // Сколько запрос ждет освобождения места (0 — отклоняется сразу)
+	Queue        QueueConfig    `yaml:"queue"`         // Очередь запросов, ожидающих бэкенд
+	Adaptive     AdaptiveConfig `yaml:"adaptive"`      // Адаптивный лимит одновременных запросов к бэкенду
+}
+
+// Алгоритмы адаптивного лимита
+const (
+	AdaptiveAIMD     = "aimd"     // аддитивное увеличение, мультипликативное уменьшение при ошибках и медленных ответах
+	AdaptiveGradient = "gradient" // лимит следует за отношением базовой и текущей задержки
+)
+
+// AdaptiveConfig содержит настройки адаптивного лимита одновременных запросов к каждому бэкенду.
+// Лимит подстраивается по задержке и ошибкам проксированных запросов; per_backend остается верхней границей.
+// Незаданные (нулевые) значения заменяются значениями по умолчанию
+type AdaptiveConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	Algorithm        string        `yaml:"algorithm"`         // aimd или gradient (пусто — aimd)
+	InitialLimit     int           `yaml:"initial_limit"`     // Начальный лимит
+	MinLimit         int           `yaml:"min_limit"`         // Нижняя граница лимита
+	MaxLimit         int           `yaml:"max_limit"`         // Верхняя граница лимита
+	LatencyThreshold time.Duration `yaml:"latency_threshold"` // aimd: ответ дольше порога считается признаком перегрузки (0 — только ошибки)
+	BackoffRatio     float64       `yaml:"backoff_ratio"`     // aimd: множитель уменьшения лимита (0..1)
+	Tolerance        float64       `yaml:"tolerance"`         // gradient: допустимый рост задержки относительно базовой (не меньше 1)
+}
+
+// DefaultPriorityClass класс приоритета запросов, не отнесенных ни к одному из классов
+const DefaultPriorityClass = "default"
+
+// QueueConfig содержит настройки очереди запросов, для которых нет свободного бэкенда
+// (все заняты или недоступны). Запросы допускаются в порядке приоритета классов, внутри класса — по очереди
+type QueueConfig struct {
+	MaxSize        int             `yaml:"max_size"`        // Максимум ожидающих запросов (0 — без ограничения)
+	PriorityHeader string          `yaml:"priority_header"` // Заголовок с именем класса, учитывается только от server.trusted_proxies (пусто — класс определяется только по ID клиента)
+	Classes        []PriorityClass `yaml:"classes"`         // Классы в порядке убывания приоритета
+}
+
+// PriorityClass класс приоритета в очереди запросов. Класс default, если он не указан,
+// добавляется последним, то есть с наименьшим приоритетом
+type PriorityClass struct {
+	Name    string        `yaml:"name"`    // Имя класса
+	Clients []string      `yaml:"clients"` // Ключи API, субъекты JWT или IP-адреса клиентов, относящихся к классу
+	Timeout time.Duration `yaml:"timeout"` // Время ожидания в очереди (0 — concurrency.queue_timeout)
 }
 
 // HealthCheckerConfig — содержит настройки проверки нод
@@ -50,6 +238,13 @@ type HealthCheckerConfig struct {
 	Enabled  bool          `yaml:"enabled"`
 	Interval time.Duration `yaml:"interval"`
 	Path     string        `yaml:"path"`
+	Rise     int           `yaml:"rise"` // Подряд успешных проверок для возврата бэкенда в ротацию
+	Fall     int           `yaml:"fall"` // Подряд неудач (проверок или запросов) для исключения бэкенда
+}
+
+// ReloadConfig содержит настройки горячей перезагрузки конфигурации
+type ReloadConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval"` // Интервал проверки изменений файла (0 — перезагрузка только по SIGHUP)
 }
 
 // LogConfig - содержит настройки slog
@@ -60,33 +255,111 @@ type LogConfig struct {
 
 // BalancerConfig содержит настройки балансировщика
 type BalancerConfig struct {
-	Backends []string `yaml:"backends"`
-	Strategy string   `yaml:"strategy"` // round-robin, least-connections, random
+	Backends       []BackendConfig      `yaml:"backends"`
+	Strategy       string               `yaml:"strategy"`        // round-robin, weighted-round-robin, least-connections, random, consistent-hash
+	ConsistentHash ConsistentHashConfig `yaml:"consistent_hash"` // Настройки стратегии consistent-hash
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit_breaker"` // Настройки circuit breaker для каждого бэкенда
+}
+
+// CircuitBreakerConfig содержит настройки circuit breaker бэкендов
+type CircuitBreakerConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	Window           time.Duration `yaml:"window"`             // Длина скользящего окна статистики
+	MinRequests      int           `yaml:"min_requests"`       // Минимум запросов в окне для размыкания
+	ErrorRate        float64       `yaml:"error_rate"`         // Доля ошибок для размыкания (0..1)
+	SlowThreshold    time.Duration `yaml:"slow_threshold"`     // Порог медленного запроса
+	SlowRate         float64       `yaml:"slow_rate"`          // Доля медленных запросов для размыкания (0..1)
+	Cooldown         time.Duration `yaml:"cooldown"`           // Время в open до перехода в half-open
+	HalfOpenRequests int           `yaml:"half_open_requests"` // Число пробных запросов в half-open
+}
+
+// ConsistentHashConfig содержит настройки кольца consistent hashing
+type ConsistentHashConfig struct {
+	VirtualNodes int    `yaml:"virtual_nodes"` // Количество виртуальных нод на единицу веса бэкенда
+	KeySource    string `yaml:"key_source"`    // Источник ключа: client-id, header, cookie, ip
+	KeyName      string `yaml:"key_name"`      // Имя заголовка или cookie для источников header и cookie
+}
+
+// BackendConfig содержит настройки отдельного бэкенда
+type BackendConfig struct {
+	ID     string `yaml:"id"`     // Идентификатор бэкенда для admin API (по умолчанию host:port из URL)
+	URL    string `yaml:"url"`    // Адрес бэкенда
+	Weight int    `yaml:"weight"` // Вес бэкенда для weighted-round-robin (по умолчанию 1)
+}
+
+// UnmarshalYAML позволяет задавать бэкенд как строкой с URL, так и объектом {url, weight}
+func (b *BackendConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		b.URL = value.Value
+		return nil
+	}
+
+	type plain BackendConfig
+	return value.Decode((*plain)(b))
+}
+
+// ProxyConfig содержит настройки проксирования запросов на бэкенды
+type ProxyConfig struct {
+	Retry RetryConfig `yaml:"retry"` // Настройки повторных попыток
+}
+
+// RetryConfig содержит настройки повтора неудачных запросов на другом бэкенде
+type RetryConfig struct {
+	MaxAttempts int      `yaml:"max_attempts"`  // Общее число попыток, включая первую (0 или 1 — без повторов)
+	StatusCodes []int    `yaml:"status_codes"`  // Коды ответа бэкенда, при которых запрос повторяется
+	Errors      []string `yaml:"errors"`        // Виды ошибок для повтора: connect, timeout, reset
+	Methods     []string `yaml:"methods"`       // Методы, которые можно повторять (по умолчанию идемпотентные)
+	MaxBodySize int64    `yaml:"max_body_size"` // Максимальный размер буферизуемого тела запроса в байтах
 }
 
 // LoadConfig — читает YAML-файл конфигурации и возвращает заполненную структуру
+// с учетом переменных окружения CLOUDCAMP_*
 func LoadConfig(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
-	}
+	return Load(path, nil)
+}
 
-	var config Config
-	if err = yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
-	}
+// Load собирает конфигурацию из источников в порядке возрастания приоритета:
+// значения по умолчанию, YAML-файл, переменные окружения CLOUDCAMP_*, флаги командной строки.
+// Пустой path означает работу без файла. Неизвестные ключи, некорректные значения
+// и все прочие проблемы возвращаются одной ошибкой *ValidationError с путями к полям
+func Load(path string, flags Overrides) (*Config, error) {
+	config := Default()
+	v := &validator{}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+
+		var root yaml.Node
+		if err = yaml.Unmarshal(data, &root); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
 
-	switch config.Env {
-	case EnvDev, EnvProd, EnvTest:
-	default:
-		return nil, fmt.Errorf("invalid environment: %s", config.Env)
+		// Сверяем структуру файла с конфигурацией, чтобы найти опечатки в ключах и неверные типы
+		v.checkNode(&root, reflect.TypeOf(Config{}), "")
+
+		if root.Kind != 0 {
+			if err = root.Decode(config); err != nil {
+				return nil, fmt.Errorf("failed to parse config file: %w", err)
+			}
+		}
 	}
 
+	v.applyEnv(config, os.LookupEnv)
+	v.applyOverrides(config, flags)
+
 	if config.Balancer.Strategy == "" {
 		config.Balancer.Strategy = "round-robin" // Default strategy
 	}
 
-	return &config, nil
+	v.validate(config)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
 }
 
 func (l *LogConfig) GetLogDir() string {
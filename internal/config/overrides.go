@@ -0,0 +1,190 @@
+package config
+
+import (
+	"CloudCamp/pkg/utils"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix префикс переменных окружения, переопределяющих конфигурацию
+const EnvPrefix = "CLOUDCAMP_"
+
+// Overrides значения полей конфигурации, заданные в обход файла (ключ — путь поля, например server.port)
+type Overrides map[string]string
+
+// Field описывает поле конфигурации, которое можно переопределить
+type Field struct {
+	Path string // путь в YAML: rate_limiter.clients
+	Env  string // переменная окружения: CLOUDCAMP_RATE_LIMITER_CLIENTS
+	Flag string // флаг командной строки: rate-limiter.clients
+}
+
+// Fields возвращает все переопределяемые поля конфигурации.
+// Вложенные структуры раскрываются, а списки и карты задаются целиком
+func Fields() []Field {
+	var fields []Field
+	walkFields(reflect.TypeOf(Config{}), "", func(path string, _ reflect.Type) {
+		fields = append(fields, Field{
+			Path: path,
+			Env:  EnvPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_")),
+			Flag: strings.ReplaceAll(path, "_", "-"),
+		})
+	})
+	return fields
+}
+
+// Default возвращает конфигурацию со значениями по умолчанию
+func Default() *Config {
+	return &Config{
+		Env: EnvDev,
+		Server: ServerConfig{
+			Port:            8080,
+			DrainTimeout:    30 * time.Second,
+			ForwardedHeader: utils.ForwardedHeaderXFF,
+		},
+		Balancer: BalancerConfig{
+			Strategy: "round-robin",
+		},
+		RateLimiter: RateLimitConfig{
+			Interval:  10 * time.Second,
+			Algorithm: AlgorithmTokenBucket,
+			Store: ClientStoreConfig{
+				Type: ClientStoreMemory,
+			},
+			Backend: LimiterBackendMemory,
+			Redis: RedisConfig{
+				Addr:       "localhost:6379",
+				KeyPrefix:  "cloudcamp:ratelimit:",
+				Timeout:    100 * time.Millisecond,
+				PoolSize:   16,
+				FailPolicy: FailPolicyOpen,
+			},
+			Auth: RateLimitAuthConfig{
+				APIKeyHeader: "X-API-Key",
+				JWT: JWTConfig{
+					Header: "Authorization",
+				},
+			},
+		},
+		Concurrency: ConcurrencyConfig{
+			Queue: QueueConfig{
+				MaxSize: 1000,
+			},
+		},
+		HealthChecker: HealthCheckerConfig{
+			Enabled:  true,
+			Interval: 15 * time.Second,
+			Path:     "/health",
+		},
+		Log: LogConfig{
+			FilePath: "./logs/app.log",
+			Dir:      "./logs",
+		},
+	}
+}
+
+// applyEnv применяет значения из переменных окружения
+func (v *validator) applyEnv(cfg *Config, lookup func(string) (string, bool)) {
+	for _, f := range Fields() {
+		if raw, ok := lookup(f.Env); ok {
+			v.applyOverride(cfg, f.Path, raw, f.Env)
+		}
+	}
+}
+
+// applyOverrides применяет значения, заданные флагами командной строки
+func (v *validator) applyOverrides(cfg *Config, overrides Overrides) {
+	flags := make(map[string]string)
+	for _, f := range Fields() {
+		flags[f.Path] = "--" + f.Flag
+	}
+
+	for _, path := range sortedKeys(overrides) {
+		source, ok := flags[path]
+		if !ok {
+			v.addf(path, "unknown field")
+			continue
+		}
+		v.applyOverride(cfg, path, overrides[path], source)
+	}
+}
+
+// applyOverride записывает строковое значение в поле конфигурации по пути
+func (v *validator) applyOverride(cfg *Config, path, raw, source string) {
+	field := reflect.ValueOf(cfg).Elem()
+	for _, key := range strings.Split(path, ".") {
+		f, ok := yamlFields(field.Type())[key]
+		if !ok {
+			v.addf(path, "unknown field")
+			return
+		}
+		field = field.FieldByIndex(f.Index)
+	}
+
+	if err := setField(field, raw); err != nil {
+		v.addf(path, "invalid %s value %q from %s", field.Type().String(), raw, source)
+	}
+}
+
+// setField разбирает строку в значение поля. Строки записываются как есть, списки
+// задаются через запятую (a,b,c) или в виде YAML/JSON, остальные типы разбираются как YAML
+func setField(field reflect.Value, raw string) error {
+	switch {
+	case field.Kind() == reflect.String:
+		field.SetString(raw)
+		return nil
+
+	case field.Kind() == reflect.Slice && !strings.HasPrefix(strings.TrimSpace(raw), "["):
+		items := reflect.MakeSlice(field.Type(), 0, 0)
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			elem := reflect.New(field.Type().Elem())
+			node := yaml.Node{Kind: yaml.ScalarNode, Value: item}
+			if err := node.Decode(elem.Interface()); err != nil {
+				return err
+			}
+			items = reflect.Append(items, elem.Elem())
+		}
+		field.Set(items)
+		return nil
+
+	default:
+		value := reflect.New(field.Type())
+		if err := yaml.Unmarshal([]byte(raw), value.Interface()); err != nil {
+			return err
+		}
+		field.Set(value.Elem())
+		return nil
+	}
+}
+
+// walkFields обходит листовые поля структуры конфигурации
+func walkFields(t reflect.Type, path string, fn func(path string, t reflect.Type)) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+
+		if f.Type.Kind() == reflect.Struct && f.Type.PkgPath() == t.PkgPath() {
+			walkFields(f.Type, joinPath(path, name), fn)
+			continue
+		}
+		fn(joinPath(path, name), f.Type)
+	}
+}
+
+// Usage возвращает описание флага для поля
+func (f Field) Usage() string {
+	return fmt.Sprintf("override %s (env %s)", f.Path, f.Env)
+}
@@ -0,0 +1,589 @@
+package config
+
+import (
+	"CloudCamp/pkg/utils"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Допустимые значения перечислимых полей конфигурации
+var (
+	validStrategies = []string{"round-robin", "weighted-round-robin", "random", "least-connections", "consistent-hash"}
+	validKeySources = []string{"client-id", "header", "cookie", "ip"}
+	validRetryErrs  = []string{"connect", "timeout", "reset"}
+	validStores     = []string{ClientStoreMemory, ClientStoreFile}
+	validBackends   = []string{LimiterBackendMemory, LimiterBackendRedis}
+	validPolicies   = []string{FailPolicyOpen, FailPolicyClosed}
+	validPolicyKeys = []string{PolicyKeyIP, PolicyKeyClientID, PolicyKeyAPIKey, PolicyKeyJWTSubject, PolicyKeyRoute}
+	validAlgorithms = []string{AlgorithmTokenBucket, AlgorithmSlidingWindowLog, AlgorithmSlidingWindowCounter, AlgorithmGCRA}
+	validAdaptive   = []string{AdaptiveAIMD, AdaptiveGradient}
+)
+
+// FieldError описывает проблему в конкретном поле конфигурации
+type FieldError struct {
+	Path    string // путь к полю в YAML, например balancer.backends[2]
+	Message string
+}
+
+// ValidationError содержит все найденные в конфигурации проблемы
+type ValidationError struct {
+	Problems []FieldError
+}
+
+// Error возвращает список всех проблем, по одной на строку
+func (e *ValidationError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("invalid config: %d problem(s)", len(e.Problems)))
+	for _, p := range e.Problems {
+		sb.WriteString("\n  ")
+		if p.Path != "" {
+			sb.WriteString(p.Path)
+			sb.WriteString(": ")
+		}
+		sb.WriteString(p.Message)
+	}
+	return sb.String()
+}
+
+// validator накапливает проблемы конфигурации
+type validator struct {
+	problems []FieldError
+}
+
+// addf добавляет проблему для поля. Для поля, уже имеющего проблему, повторная не добавляется
+func (v *validator) addf(path, format string, args ...any) {
+	for _, p := range v.problems {
+		if p.Path == path {
+			return
+		}
+	}
+	v.problems = append(v.problems, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// err возвращает ValidationError, если проблемы найдены
+func (v *validator) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: v.problems}
+}
+
+// checkNode сверяет дерево YAML со структурой конфигурации: находит неизвестные ключи
+// и значения, которые нельзя привести к типу поля. Некорректные значения заменяются на null,
+// чтобы декодирование остальной конфигурации прошло и проверка значений нашла все проблемы
+func (v *validator) checkNode(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind == yaml.DocumentNode {
+		for _, child := range node.Content {
+			v.checkNode(child, t, path)
+		}
+		return
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Tag == "!!null" {
+		return
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	// Бэкенд может быть задан строкой с URL
+	if t == reflect.TypeOf(BackendConfig{}) && node.Kind == yaml.ScalarNode {
+		return
+	}
+
+	switch {
+	case t == reflect.TypeOf(time.Duration(0)):
+		v.checkScalar(node, t, path)
+
+	case t.Kind() == reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			v.addf(path, "expected mapping")
+			resetNode(node)
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+			field, ok := fields[key]
+			if !ok {
+				v.addf(joinPath(path, key), "unknown field (known: %s)", strings.Join(sortedKeys(fields), ", "))
+				continue
+			}
+			v.checkNode(value, field.Type, joinPath(path, key))
+		}
+
+	case t.Kind() == reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			v.addf(path, "expected list")
+			resetNode(node)
+			return
+		}
+		for i, item := range node.Content {
+			v.checkNode(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+
+	case t.Kind() == reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			v.addf(path, "expected mapping")
+			resetNode(node)
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.checkNode(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value))
+		}
+
+	default:
+		v.checkScalar(node, t, path)
+	}
+}
+
+// checkScalar проверяет, что скалярное значение приводится к типу поля
+func (v *validator) checkScalar(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind != yaml.ScalarNode {
+		v.addf(path, "expected scalar value")
+		resetNode(node)
+		return
+	}
+	if err := node.Decode(reflect.New(t).Interface()); err != nil {
+		v.addf(path, "invalid %s value %q", t.String(), node.Value)
+		resetNode(node)
+	}
+}
+
+// resetNode заменяет значение узла на null
+func resetNode(node *yaml.Node) {
+	*node = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Line: node.Line, Column: node.Column}
+}
+
+// validate проверяет значения полей загруженной конфигурации
+func (v *validator) validate(cfg *Config) {
+	switch cfg.Env {
+	case EnvDev, EnvProd, EnvTest:
+	default:
+		v.addf("env", "invalid environment %q (expected %s, %s or %s)", cfg.Env, EnvDev, EnvProd, EnvTest)
+	}
+
+	if cfg.Server.Port < 1 || cfg.Server.Port > 65535 {
+		v.addf("server.port", "port must be between 1 and 65535, got %d", cfg.Server.Port)
+	}
+	v.nonNegative("server.drain_timeout", cfg.Server.DrainTimeout)
+	for i, proxy := range cfg.Server.TrustedProxies {
+		if _, err := utils.ParseNetwork(proxy); err != nil {
+			v.addf(fmt.Sprintf("server.trusted_proxies[%d]", i), "%v", err)
+		}
+	}
+	if !contains(utils.ForwardedHeaders, cfg.Server.ForwardedHeader) {
+		v.addf("server.forwarded_header", "unknown header %q (expected one of: %s)", cfg.Server.ForwardedHeader, strings.Join(utils.ForwardedHeaders, ", "))
+	}
+
+	v.validateAdmin(cfg.Admin)
+
+	v.validateBalancer(cfg.Balancer)
+	v.validateProxy(cfg.Proxy)
+
+	v.validateConcurrency(cfg.Concurrency)
+
+	hc := cfg.HealthChecker
+	v.positive("health_checker.interval", hc.Interval)
+	if !strings.HasPrefix(hc.Path, "/") {
+		v.addf("health_checker.path", "path must start with '/', got %q", hc.Path)
+	}
+	v.nonNegativeInt("health_checker.rise", hc.Rise)
+	v.nonNegativeInt("health_checker.fall", hc.Fall)
+
+	rl := cfg.RateLimiter
+	v.positive("rate_limiter.interval", rl.Interval)
+	if rl.Enabled {
+		if rl.Rate <= 0 {
+			v.addf("rate_limiter.rate", "rate must be positive, got %d", rl.Rate)
+		}
+		v.positive("rate_limiter.period", rl.Period)
+	}
+	v.validateAlgorithm("rate_limiter.algorithm", rl.Algorithm, rl.Backend)
+	if rl.Store.Type != "" && !contains(validStores, rl.Store.Type) {
+		v.addf("rate_limiter.store.type", "unknown store type %q (expected one of: %s)", rl.Store.Type, strings.Join(validStores, ", "))
+	}
+	if rl.Store.Type == ClientStoreFile && rl.Store.Path == "" {
+		v.addf("rate_limiter.store.path", "path is required for file store")
+	}
+	if rl.Backend != "" && !contains(validBackends, rl.Backend) {
+		v.addf("rate_limiter.backend", "unknown backend %q (expected one of: %s)", rl.Backend, strings.Join(validBackends, ", "))
+	}
+	if rl.Backend == LimiterBackendRedis {
+		v.validateRedis(rl.Redis)
+	}
+	for _, clientID := range sortedKeys(rl.Clients) {
+		limit := rl.Clients[clientID]
+		path := joinPath("rate_limiter.clients", clientID)
+		if limit.RateLimit <= 0 {
+			v.addf(path+".rate", "rate must be positive, got %d", limit.RateLimit)
+		}
+		v.positive(path+".period", limit.Period)
+		v.validateAlgorithm(path+".algorithm", limit.Algorithm, rl.Backend)
+	}
+	v.validatePolicies(rl)
+
+	if cfg.Log.FilePath == "" {
+		v.addf("log.file_path", "log file path is required")
+	}
+	if cfg.Log.Dir == "" {
+		v.addf("log.dir", "log directory is required")
+	}
+
+	v.nonNegative("reload.poll_interval", cfg.Reload.PollInterval)
+}
+
+// validateAdmin проверяет настройки admin listener. Доступ по TCP требует токен или mTLS,
+// unix-сокет защищается правами файловой системы
+func (v *validator) validateAdmin(a AdminConfig) {
+	if a.Addr == "" {
+		return
+	}
+
+	if a.IsUnix() {
+		if a.SocketPath() == "" {
+			v.addf("admin.addr", "unix socket path is required")
+		}
+	} else {
+		_, port, err := net.SplitHostPort(a.Addr)
+		if n, convErr := strconv.Atoi(port); err != nil || convErr != nil || n < 1 || n > 65535 {
+			v.addf("admin.addr", "invalid address %q (expected host:port or unix:/path)", a.Addr)
+		}
+		if a.Token == "" && a.TLS.ClientCAFile == "" {
+			v.addf("admin.token", "token or admin.tls.client_ca_file is required for a TCP admin address")
+		}
+	}
+
+	if (a.TLS.CertFile == "") != (a.TLS.KeyFile == "") {
+		v.addf("admin.tls", "cert_file and key_file must be set together")
+	}
+	if a.TLS.ClientCAFile != "" && a.TLS.CertFile == "" {
+		v.addf("admin.tls.client_ca_file", "client certificate verification requires cert_file and key_file")
+	}
+}
+
+// validateAlgorithm проверяет алгоритм лимита. Распределенный лимитер поддерживает только token-bucket
+func (v *validator) validateAlgorithm(path, algorithm, backend string) {
+	if algorithm == "" {
+		return
+	}
+	if !contains(validAlgorithms, algorithm) {
+		v.addf(path, "unknown algorithm %q (expected one of: %s)", algorithm, strings.Join(validAlgorithms, ", "))
+		return
+	}
+	if backend == LimiterBackendRedis && algorithm != AlgorithmTokenBucket {
+		v.addf(path, "algorithm %q is not supported by the redis backend (only %s)", algorithm, AlgorithmTokenBucket)
+	}
+}
+
+// validatePolicies проверяет политики лимитов и настройки аутентификации, от которых они зависят
+func (v *validator) validatePolicies(rl RateLimitConfig) {
+	auth := rl.Auth
+	if len(auth.APIKeys) > 0 && auth.APIKeyHeader == "" {
+		v.addf("rate_limiter.auth.api_key_header", "header is required when api_keys are set")
+	}
+	for _, clientID := range sortedKeys(auth.APIKeys) {
+		if auth.APIKeys[clientID] == "" {
+			v.addf(joinPath("rate_limiter.auth.api_keys", clientID), "api key must not be empty")
+		}
+	}
+	if auth.JWT.Enabled() && auth.JWT.Header == "" {
+		v.addf("rate_limiter.auth.jwt.header", "header is required when jwt is enabled")
+	}
+
+	names := make(map[string]bool)
+	for i, p := range rl.Policies {
+		path := fmt.Sprintf("rate_limiter.policies[%d]", i)
+		switch {
+		case p.Name == "":
+			v.addf(path+".name", "name is required")
+		case strings.Contains(p.Name, ":"):
+			v.addf(path+".name", "name must not contain ':', got %q", p.Name)
+		case names[p.Name]:
+			v.addf(path+".name", "duplicate policy name %q", p.Name)
+		}
+		names[p.Name] = true
+
+		if p.Match.PathPrefix != "" && !strings.HasPrefix(p.Match.PathPrefix, "/") {
+			v.addf(path+".match.path_prefix", "path prefix must start with '/', got %q", p.Match.PathPrefix)
+		}
+		if len(p.Match.APIKeys) > 0 && len(auth.APIKeys) == 0 {
+			v.addf(path+".match.api_keys", "rate_limiter.auth.api_keys must be set to match on api keys")
+		}
+		if len(p.Match.JWTSubjects) > 0 && !auth.JWT.Enabled() {
+			v.addf(path+".match.jwt_subjects", "rate_limiter.auth.jwt must be configured to match on jwt subjects")
+		}
+
+		switch {
+		case p.Key == PolicyKeyAPIKey && len(auth.APIKeys) == 0:
+			v.addf(path+".key", "rate_limiter.auth.api_keys must be set to count by api key")
+		case p.Key == PolicyKeyJWTSubject && !auth.JWT.Enabled():
+			v.addf(path+".key", "rate_limiter.auth.jwt must be configured to count by jwt subject")
+		case strings.HasPrefix(p.Key, PolicyKeyHeader):
+			if strings.TrimPrefix(p.Key, PolicyKeyHeader) == "" {
+				v.addf(path+".key", "header name is required, e.g. header:X-Tenant-ID")
+			}
+		case !contains(validPolicyKeys, p.Key):
+			v.addf(path+".key", "unknown key %q (expected one of: %s, header:<name>)", p.Key, strings.Join(validPolicyKeys, ", "))
+		}
+
+		if p.Rate <= 0 {
+			v.addf(path+".rate", "rate must be positive, got %d", p.Rate)
+		}
+		v.positive(path+".period", p.Period)
+		v.validateAlgorithm(path+".algorithm", p.Algorithm, rl.Backend)
+	}
+}
+
+// validateRedis проверяет настройки распределенного rate limiter
+func (v *validator) validateRedis(r RedisConfig) {
+	if _, port, err := net.SplitHostPort(r.Addr); err != nil || port == "" {
+		v.addf("rate_limiter.redis.addr", "invalid address %q (expected host:port)", r.Addr)
+	}
+	if r.DB < 0 {
+		v.addf("rate_limiter.redis.db", "db must not be negative, got %d", r.DB)
+	}
+	v.positive("rate_limiter.redis.timeout", r.Timeout)
+	if r.PoolSize < 1 {
+		v.addf("rate_limiter.redis.pool_size", "pool size must be positive, got %d", r.PoolSize)
+	}
+	if !contains(validPolicies, r.FailPolicy) {
+		v.addf("rate_limiter.redis.fail_policy", "unknown fail policy %q (expected one of: %s)", r.FailPolicy, strings.Join(validPolicies, ", "))
+	}
+}
+
+// validateConcurrency проверяет настройки ограничения одновременных запросов
+func (v *validator) validateConcurrency(c ConcurrencyConfig) {
+	v.nonNegativeInt("concurrency.per_client", c.PerClient)
+	v.nonNegativeInt("concurrency.per_backend", c.PerBackend)
+	for _, clientID := range sortedKeys(c.Clients) {
+		v.nonNegativeInt("concurrency.clients."+clientID, c.Clients[clientID])
+	}
+	v.nonNegative("concurrency.queue_timeout", c.QueueTimeout)
+
+	v.nonNegativeInt("concurrency.queue.max_size", c.Queue.MaxSize)
+
+	names := make(map[string]bool)
+	for i, class := range c.Queue.Classes {
+		path := fmt.Sprintf("concurrency.queue.classes[%d]", i)
+		switch {
+		case class.Name == "":
+			v.addf(path+".name", "name is required")
+		case names[class.Name]:
+			v.addf(path+".name", "duplicate class name %q", class.Name)
+		}
+		names[class.Name] = true
+		v.nonNegative(path+".timeout", class.Timeout)
+	}
+
+	v.validateAdaptive(c.Adaptive)
+}
+
+// validateAdaptive проверяет настройки адаптивного лимита одновременных запросов к бэкенду
+func (v *validator) validateAdaptive(a AdaptiveConfig) {
+	if a.Algorithm != "" && !contains(validAdaptive, a.Algorithm) {
+		v.addf("concurrency.adaptive.algorithm", "unknown algorithm %q (expected one of: %s)", a.Algorithm, strings.Join(validAdaptive, ", "))
+	}
+	v.nonNegativeInt("concurrency.adaptive.initial_limit", a.InitialLimit)
+	v.nonNegativeInt("concurrency.adaptive.min_limit", a.MinLimit)
+	v.nonNegativeInt("concurrency.adaptive.max_limit", a.MaxLimit)
+	if a.MaxLimit > 0 && a.MinLimit > a.MaxLimit {
+		v.addf("concurrency.adaptive.max_limit", "max limit %d is less than min limit %d", a.MaxLimit, a.MinLimit)
+	}
+	v.nonNegative("concurrency.adaptive.latency_threshold", a.LatencyThreshold)
+	if a.BackoffRatio < 0 || a.BackoffRatio >= 1 {
+		v.addf("concurrency.adaptive.backoff_ratio", "must be in [0, 1), got %s", strconv.FormatFloat(a.BackoffRatio, 'g', -1, 64))
+	}
+	if a.Tolerance != 0 && a.Tolerance < 1 {
+		v.addf("concurrency.adaptive.tolerance", "must be at least 1, got %s", strconv.FormatFloat(a.Tolerance, 'g', -1, 64))
+	}
+}
+
+// validateBalancer проверяет настройки балансировщика
+func (v *validator) validateBalancer(b BalancerConfig) {
+	if !contains(validStrategies, b.Strategy) {
+		v.addf("balancer.strategy", "unknown strategy %q (expected one of: %s)", b.Strategy, strings.Join(validStrategies, ", "))
+	}
+
+	seenURL := make(map[string]int)
+	seenID := make(map[string]int)
+	for i, bc := range b.Backends {
+		path := fmt.Sprintf("balancer.backends[%d]", i)
+
+		u, err := url.Parse(bc.URL)
+		switch {
+		case bc.URL == "":
+			v.addf(path, "backend URL is required")
+		case err != nil:
+			v.addf(path, "invalid backend URL %q: %v", bc.URL, err)
+		case u.Scheme != "http" && u.Scheme != "https":
+			v.addf(path, "backend URL %q must have http or https scheme", bc.URL)
+		case u.Host == "":
+			v.addf(path, "backend URL %q must have a host", bc.URL)
+		}
+
+		if prev, ok := seenURL[bc.URL]; ok && bc.URL != "" {
+			v.addf(path, "duplicate backend URL %q (already used in balancer.backends[%d])", bc.URL, prev)
+		}
+		seenURL[bc.URL] = i
+
+		if bc.ID != "" {
+			if prev, ok := seenID[bc.ID]; ok {
+				v.addf(path+".id", "duplicate backend id %q (already used in balancer.backends[%d])", bc.ID, prev)
+			}
+			seenID[bc.ID] = i
+		}
+
+		v.nonNegativeInt(path+".weight", bc.Weight)
+	}
+
+	ch := b.ConsistentHash
+	v.nonNegativeInt("balancer.consistent_hash.virtual_nodes", ch.VirtualNodes)
+	if ch.KeySource != "" && !contains(validKeySources, ch.KeySource) {
+		v.addf("balancer.consistent_hash.key_source", "unknown key source %q (expected one of: %s)", ch.KeySource, strings.Join(validKeySources, ", "))
+	}
+	if (ch.KeySource == "header" || ch.KeySource == "cookie") && ch.KeyName == "" {
+		v.addf("balancer.consistent_hash.key_name", "key name is required for key source %q", ch.KeySource)
+	}
+
+	cb := b.CircuitBreaker
+	v.nonNegative("balancer.circuit_breaker.window", cb.Window)
+	v.nonNegativeInt("balancer.circuit_breaker.min_requests", cb.MinRequests)
+	v.ratio("balancer.circuit_breaker.error_rate", cb.ErrorRate)
+	v.nonNegative("balancer.circuit_breaker.slow_threshold", cb.SlowThreshold)
+	v.ratio("balancer.circuit_breaker.slow_rate", cb.SlowRate)
+	v.nonNegative("balancer.circuit_breaker.cooldown", cb.Cooldown)
+	v.nonNegativeInt("balancer.circuit_breaker.half_open_requests", cb.HalfOpenRequests)
+}
+
+// validateProxy проверяет настройки проксирования
+func (v *validator) validateProxy(p ProxyConfig) {
+	r := p.Retry
+	v.nonNegativeInt("proxy.retry.max_attempts", r.MaxAttempts)
+	for i, code := range r.StatusCodes {
+		if code < 100 || code > 599 {
+			v.addf(fmt.Sprintf("proxy.retry.status_codes[%d]", i), "invalid HTTP status code %d", code)
+		}
+	}
+	for i, e := range r.Errors {
+		if !contains(validRetryErrs, e) {
+			v.addf(fmt.Sprintf("proxy.retry.errors[%d]", i), "unknown error kind %q (expected one of: %s)", e, strings.Join(validRetryErrs, ", "))
+		}
+	}
+	for i, m := range r.Methods {
+		if !validMethod(m) {
+			v.addf(fmt.Sprintf("proxy.retry.methods[%d]", i), "invalid HTTP method %q", m)
+		}
+	}
+	if r.MaxBodySize < 0 {
+		v.addf("proxy.retry.max_body_size", "must not be negative, got %d", r.MaxBodySize)
+	}
+}
+
+// positive проверяет, что длительность больше нуля
+func (v *validator) positive(path string, d time.Duration) {
+	if d <= 0 {
+		v.addf(path, "duration must be positive, got %s", d)
+	}
+}
+
+// nonNegative проверяет, что длительность не отрицательна
+func (v *validator) nonNegative(path string, d time.Duration) {
+	if d < 0 {
+		v.addf(path, "duration must not be negative, got %s", d)
+	}
+}
+
+// nonNegativeInt проверяет, что число не отрицательно
+func (v *validator) nonNegativeInt(path string, n int) {
+	if n < 0 {
+		v.addf(path, "must not be negative, got %d", n)
+	}
+}
+
+// ratio проверяет, что доля лежит в диапазоне [0, 1]
+func (v *validator) ratio(path string, f float64) {
+	if f < 0 || f > 1 {
+		v.addf(path, "must be between 0 and 1, got %s", strconv.FormatFloat(f, 'g', -1, 64))
+	}
+}
+
+// Validate проверяет значения полей конфигурации и возвращает все найденные проблемы
+func Validate(cfg *Config) error {
+	v := &validator{}
+	v.validate(cfg)
+	return v.err()
+}
+
+// yamlFields возвращает поля структуры по их YAML-именам
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// joinPath добавляет ключ к пути поля
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// sortedKeys возвращает отсортированные ключи карты
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// contains проверяет наличие строки в списке
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validMethod проверяет, что строка является корректным токеном HTTP-метода
+func validMethod(m string) bool {
+	if m == "" {
+		return false
+	}
+	switch strings.ToUpper(m) {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package balancerDomain
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LimitAlgorithm алгоритм адаптивного лимита одновременных запросов
+type LimitAlgorithm int32
+
+const (
+	LimitAIMD     LimitAlgorithm = iota // лимит растет на 1 за «окно» успешных запросов и мультипликативно уменьшается при перегрузке
+	LimitGradient                       // лимит следует за отношением базовой задержки к текущей
+)
+
+// String возвращает название алгоритма
+func (a LimitAlgorithm) String() string {
+	switch a {
+	case LimitAIMD:
+		return "aimd"
+	case LimitGradient:
+		return "gradient"
+	default:
+		return "unknown"
+	}
+}
+
+// Значения настроек адаптивного лимита по умолчанию
+const (
+	defaultAdaptiveInitial   = 20
+	defaultAdaptiveMin       = 1
+	defaultAdaptiveMax       = 1000
+	defaultAdaptiveBackoff   = 0.9
+	defaultAdaptiveTolerance = 1.5
+)
+
+// Параметры алгоритма gradient
+const (
+	gradientShortWindow = 10   // число запросов, по которым усредняется текущая задержка
+	gradientLongWindow  = 600  // число запросов, по которым усредняется базовая задержка
+	gradientSmoothing   = 0.2  // доля нового значения лимита при сглаживании
+	gradientMinRatio    = 0.5  // максимальное уменьшение лимита за один запрос
+	gradientDriftRatio  = 2.0  // при текущей задержке в столько раз ниже базовой базовая быстро снижается
+	gradientDriftDecay  = 0.95 // множитель быстрого снижения базовой задержки
+)
+
+// AdaptiveLimitSettings содержит настройки адаптивного лимита
+type AdaptiveLimitSettings struct {
+	Algorithm        LimitAlgorithm
+	InitialLimit     int           // начальный лимит
+	MinLimit         int           // нижняя граница лимита
+	MaxLimit         int           // верхняя граница лимита
+	LatencyThreshold time.Duration // aimd: ответ дольше порога считается признаком перегрузки (0 — только ошибки)
+	BackoffRatio     float64       // aimd: множитель уменьшения лимита при перегрузке
+	Tolerance        float64       // gradient: допустимый рост задержки относительно базовой
+}
+
+// AdaptiveLimit подстраивает допустимое число одновременных запросов к бэкенду по задержке
+// и ошибкам завершенных запросов: лимит снижается раньше, чем бэкенд перестает справляться,
+// и растет, пока бэкенд отвечает быстро
+type AdaptiveLimit struct {
+	mu       sync.Mutex
+	settings AdaptiveLimitSettings
+	limit    float64
+	shortRTT float64 // скользящее среднее задержки по последним запросам, нс
+	longRTT  float64 // скользящее среднее задержки без перегрузки (базовая), нс
+
+	backoffPending int64     // aimd: запросы, выполнявшиеся в момент последнего уменьшения и еще не завершенные
+	backoffUntil   time.Time // aimd: до этого момента повторные неудачи не уменьшают лимит
+
+	current atomic.Int64 // целая часть limit для чтения без мьютекса
+}
+
+// NewAdaptiveLimit создает адаптивный лимит, подставляя значения по умолчанию для незаданных настроек
+func NewAdaptiveLimit(settings AdaptiveLimitSettings) *AdaptiveLimit {
+	if settings.MinLimit <= 0 {
+		settings.MinLimit = defaultAdaptiveMin
+	}
+	if settings.MaxLimit <= 0 {
+		settings.MaxLimit = max(defaultAdaptiveMax, settings.MinLimit)
+	}
+	if settings.InitialLimit <= 0 {
+		settings.InitialLimit = defaultAdaptiveInitial
+	}
+	if settings.BackoffRatio <= 0 || settings.BackoffRatio >= 1 {
+		settings.BackoffRatio = defaultAdaptiveBackoff
+	}
+	if settings.Tolerance < 1 {
+		settings.Tolerance = defaultAdaptiveTolerance
+	}
+
+	l := &AdaptiveLimit{settings: settings}
+	l.set(float64(settings.InitialLimit))
+	return l
+}
+
+// Algorithm возвращает алгоритм лимита
+func (l *AdaptiveLimit) Algorithm() LimitAlgorithm {
+	return l.settings.Algorithm
+}
+
+// Limit возвращает текущий лимит одновременных запросов
+func (l *AdaptiveLimit) Limit() int {
+	return int(l.current.Load())
+}
+
+// Record учитывает результат завершенного запроса. inFlight — число выполнявшихся
+// на бэкенде запросов, включая этот
+func (l *AdaptiveLimit) Record(failed bool, latency time.Duration, inFlight int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch l.settings.Algorithm {
+	case LimitGradient:
+		l.gradient(failed, latency, inFlight)
+	default:
+		l.aimd(failed, latency, inFlight)
+	}
+}
+
+// aimd уменьшает лимит в BackoffRatio раз при ошибке или медленном ответе,
+// иначе увеличивает его так, что за limit успешных запросов он вырастает примерно на 1.
+// Неудачи запросов, выполнявшихся одновременно, — одно событие перегрузки: после уменьшения
+// лимит не уменьшается снова, пока эти запросы не завершатся или не пройдет время одного ответа
+func (l *AdaptiveLimit) aimd(failed bool, latency time.Duration, inFlight int64) {
+	if failed || (l.settings.LatencyThreshold > 0 && latency > l.settings.LatencyThreshold) {
+		now := time.Now()
+		if l.backoffPending > 0 && now.Before(l.backoffUntil) {
+			l.backoffPending--
+			return
+		}
+		l.backoffPending = inFlight - 1
+		l.backoffUntil = now.Add(max(latency, time.Duration(l.shortRTT)))
+		l.set(l.limit * l.settings.BackoffRatio)
+		return
+	}
+
+	if l.backoffPending > 0 {
+		l.backoffPending--
+	}
+	l.shortRTT = ewma(l.shortRTT, float64(latency), gradientShortWindow)
+	if l.limited(inFlight) {
+		l.set(l.limit + 1/l.limit)
+	}
+}
+
+// gradient сравнивает текущую задержку с базовой: пока текущая не превышает базовую более чем
+// в Tolerance раз, лимит растет на sqrt(limit), иначе уменьшается пропорционально росту задержки.
+// Ошибка считается перегрузкой: лимит уменьшается как при максимальном росте задержки, но без прибавки.
+// Изменения сглаживаются, чтобы единичные выбросы не обрушивали лимит
+func (l *AdaptiveLimit) gradient(failed bool, latency time.Duration, inFlight int64) {
+	if failed {
+		// Задержка неудачных запросов (например, отказа в соединении) не отражает загрузку бэкенда
+		l.smooth(l.limit * gradientMinRatio)
+		return
+	}
+
+	rtt := float64(max(latency, time.Microsecond))
+	if l.longRTT == 0 {
+		l.shortRTT, l.longRTT = rtt, rtt
+	}
+	l.shortRTT = ewma(l.shortRTT, rtt, gradientShortWindow)
+	l.longRTT = ewma(l.longRTT, rtt, gradientLongWindow)
+	if l.longRTT/l.shortRTT > gradientDriftRatio {
+		l.longRTT *= gradientDriftDecay
+	}
+
+	if !l.limited(inFlight) {
+		return
+	}
+	ratio := max(gradientMinRatio, min(1, l.settings.Tolerance*l.longRTT/l.shortRTT))
+	l.smooth(l.limit*ratio + math.Sqrt(l.limit))
+}
+
+// smooth сдвигает лимит к next на долю gradientSmoothing. Вызывается под мьютексом
+func (l *AdaptiveLimit) smooth(next float64) {
+	l.set(l.limit*(1-gradientSmoothing) + next*gradientSmoothing)
+}
+
+// limited проверяет, нагружен ли бэкенд настолько, что лимит имеет смысл увеличивать:
+// при малом числе запросов их задержка ничего не говорит о запасе мощности
+func (l *AdaptiveLimit) limited(inFlight int64) bool {
+	return float64(inFlight)*2 >= l.limit
+}
+
+// set устанавливает лимит в пределах [MinLimit, MaxLimit]. Вызывается под мьютексом
+func (l *AdaptiveLimit) set(limit float64) {
+	l.limit = max(float64(l.settings.MinLimit), min(float64(l.settings.MaxLimit), limit))
+	l.current.Store(int64(l.limit))
+}
+
+// ewma добавляет значение в экспоненциальное скользящее среднее по window последним значениям
+func ewma(avg, value float64, window int) float64 {
+	alpha := 2 / float64(window+1)
+	return avg + alpha*(value-avg)
+}
@@ -1,19 +1,55 @@
 package balancerDomain
 
-import "sync/atomic"
+import (
+	"context"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// drainPollInterval интервал проверки числа активных соединений при ожидании вывода из ротации
+const drainPollInterval = 50 * time.Millisecond
+
+// Пороги пассивной проверки здоровья по умолчанию
+const (
+	DefaultFallThreshold = 3 // подряд неудач, после которых бэкенд помечается недоступным
+	DefaultRiseThreshold = 2 // подряд успехов, после которых бэкенд снова становится доступным
+)
 
 // Backend представляет собой отдельный сервер в пуле балансировки
 type Backend struct {
+	ID                string       // идентификатор бэкенда (по умолчанию host:port из URL)
 	URL               string       // адрес бэкенд сервера
+	Weight            int          // вес бэкенда для взвешенных стратегий
 	Alive             atomic.Bool  // показывает, доступен ли сервер
 	ActiveConnections atomic.Int64 // текущее количество активных соединений
+	Draining          atomic.Bool  // бэкенд выводится из ротации: новые запросы не направляются, текущие завершаются
+
+	fall      atomic.Int64 // порог подряд идущих неудач
+	rise      atomic.Int64 // порог подряд идущих успехов
+	failures  atomic.Int64 // текущее число подряд идущих неудач
+	successes atomic.Int64 // текущее число подряд идущих успехов
+
+	maxConnections atomic.Int64                  // максимум одновременных запросов (0 — без ограничения)
+	adaptive       atomic.Pointer[AdaptiveLimit] // адаптивный лимит одновременных запросов (nil — выключен)
+
+	breaker atomic.Pointer[CircuitBreaker] // circuit breaker бэкенда (nil — выключен)
 }
 
-func NewBackend(url string) *Backend {
+// NewBackend создает новый бэкенд с указанным весом (вес меньше 1 приводится к 1)
+func NewBackend(rawURL string, weight int) *Backend {
+	if weight < 1 {
+		weight = 1
+	}
+
 	b := &Backend{
-		URL: url,
+		ID:     backendID(rawURL),
+		URL:    rawURL,
+		Weight: weight,
 	}
 	b.Alive.Store(true)
+	b.SetHealthThresholds(DefaultRiseThreshold, DefaultFallThreshold)
 
 	return b
 }
@@ -23,6 +59,80 @@ func (b *Backend) IsAlive() bool {
 	return b.Alive.Load()
 }
 
+// IsAvailable проверяет, можно ли направлять на бэкенд новые запросы:
+// бэкенд жив, не выводится из ротации и его цепь не разомкнута
+func (b *Backend) IsAvailable() bool {
+	if !b.IsAlive() || b.IsDraining() {
+		return false
+	}
+	if cb := b.breaker.Load(); cb != nil {
+		return cb.Ready()
+	}
+	return true
+}
+
+// IsDraining проверяет, выводится ли бэкенд из ротации
+func (b *Backend) IsDraining() bool {
+	return b.Draining.Load()
+}
+
+// SetDraining включает или выключает режим вывода из ротации.
+// В отличие от Alive, этот флаг не меняется проверками здоровья
+func (b *Backend) SetDraining(draining bool) {
+	b.Draining.Store(draining)
+}
+
+// WaitDrained ожидает, пока число активных соединений не станет равным нулю, или отмены контекста
+func (b *Backend) WaitDrained(ctx context.Context) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for b.GetActiveConnections() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// SetCircuitBreaker устанавливает circuit breaker бэкенда (nil выключает его)
+func (b *Backend) SetCircuitBreaker(cb *CircuitBreaker) {
+	b.breaker.Store(cb)
+}
+
+// CircuitBreaker возвращает circuit breaker бэкенда или nil
+func (b *Backend) CircuitBreaker() *CircuitBreaker {
+	return b.breaker.Load()
+}
+
+// AllowRequest резервирует бэкенд под запрос с учетом состояния circuit breaker
+func (b *Backend) AllowRequest() bool {
+	if cb := b.breaker.Load(); cb != nil {
+		return cb.Begin()
+	}
+	return true
+}
+
+// AbortRequest отменяет резервирование AllowRequest для запроса, результат которого не учитывается
+func (b *Backend) AbortRequest() {
+	if cb := b.breaker.Load(); cb != nil {
+		cb.Cancel()
+	}
+}
+
+// RecordResult передает результат проксированного запроса в circuit breaker и адаптивный лимит.
+// Вызывается до освобождения места запроса
+func (b *Backend) RecordResult(failed bool, latency time.Duration) {
+	if cb := b.breaker.Load(); cb != nil {
+		cb.Record(failed, latency)
+	}
+	if l := b.adaptive.Load(); l != nil {
+		l.Record(failed, latency, b.GetActiveConnections())
+	}
+}
+
 // SetAlive устанавливает статус доступности бэкенда
 func (b *Backend) SetAlive(alive bool) {
 	b.Alive.Store(alive)
@@ -38,7 +148,116 @@ func (b *Backend) DecrementConnections() {
 	b.ActiveConnections.Add(-1)
 }
 
+// TryIncrementConnections увеличивает счетчик активных соединений, если не достигнут максимум.
+// Возвращает false, если бэкенд занят
+func (b *Backend) TryIncrementConnections() bool {
+	for {
+		n := b.ActiveConnections.Load()
+		if limit := int64(b.MaxConnections()); limit > 0 && n >= limit {
+			return false
+		}
+		if b.ActiveConnections.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// SetMaxConnections устанавливает максимум одновременных запросов (0 — без ограничения).
+// Уже выполняющиеся запросы не прерываются
+func (b *Backend) SetMaxConnections(limit int) {
+	b.maxConnections.Store(int64(max(limit, 0)))
+}
+
+// MaxConnections возвращает действующий максимум одновременных запросов: меньший из статического
+// и адаптивного лимитов (0 — без ограничения)
+func (b *Backend) MaxConnections() int {
+	limit := int(b.maxConnections.Load())
+	if l := b.adaptive.Load(); l != nil && (limit == 0 || l.Limit() < limit) {
+		limit = l.Limit()
+	}
+	return limit
+}
+
+// SetAdaptiveLimit устанавливает адаптивный лимит одновременных запросов (nil выключает его)
+func (b *Backend) SetAdaptiveLimit(l *AdaptiveLimit) {
+	b.adaptive.Store(l)
+}
+
+// AdaptiveLimit возвращает адаптивный лимит бэкенда или nil
+func (b *Backend) AdaptiveLimit() *AdaptiveLimit {
+	return b.adaptive.Load()
+}
+
 // GetActiveConnections возвращает количество активных соединений
 func (b *Backend) GetActiveConnections() int64 {
 	return b.ActiveConnections.Load()
 }
+
+// SetHealthThresholds устанавливает пороги rise/fall (значения меньше 1 игнорируются)
+func (b *Backend) SetHealthThresholds(rise, fall int) {
+	if rise > 0 {
+		b.rise.Store(int64(rise))
+	}
+	if fall > 0 {
+		b.fall.Store(int64(fall))
+	}
+}
+
+// ReportFailure учитывает неудачу (ошибку живого трафика или проверки здоровья).
+// Возвращает true, если бэкенд был помечен недоступным в результате этого вызова
+func (b *Backend) ReportFailure() bool {
+	b.successes.Store(0)
+	if b.failures.Add(1) < b.fall.Load() {
+		return false
+	}
+	return b.Alive.CompareAndSwap(true, false)
+}
+
+// ReportSuccess учитывает успешный запрос или проверку.
+// Возвращает true, если бэкенд был возвращен в ротацию в результате этого вызова
+func (b *Backend) ReportSuccess() bool {
+	b.failures.Store(0)
+	if b.IsAlive() {
+		return false
+	}
+	if b.successes.Add(1) < b.rise.Load() {
+		return false
+	}
+	b.successes.Store(0)
+	return b.Alive.CompareAndSwap(false, true)
+}
+
+// backendID возвращает идентификатор бэкенда по умолчанию — host:port из URL
+func backendID(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return rawURL
+}
+
+// DrainBackends выводит бэкенды из ротации и ожидает завершения их активных соединений.
+// Возвращает ошибку контекста, если хотя бы один бэкенд не успел освободиться
+func DrainBackends(ctx context.Context, backends []*Backend) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		last error
+	)
+
+	for _, b := range backends {
+		b.SetDraining(true)
+
+		wg.Add(1)
+		go func(b *Backend) {
+			defer wg.Done()
+			if err := b.WaitDrained(ctx); err != nil {
+				mu.Lock()
+				last = err
+				mu.Unlock()
+			}
+		}(b)
+	}
+	wg.Wait()
+
+	return last
+}
@@ -29,14 +29,14 @@ func (b *BaseBalancer) UpdateBackends(backends []*Backend) {
 	b.backends = backends
 }
 
-// GetAvailableBackends возвращает список доступных бэкендов
+// GetAvailableBackends возвращает список доступных бэкендов (живых и с неразомкнутой цепью)
 func (b *BaseBalancer) GetAvailableBackends() []*Backend {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
 	var available []*Backend
 	for _, backend := range b.backends {
-		if backend.IsAlive() {
+		if backend.IsAvailable() {
 			available = append(available, backend)
 		}
 	}
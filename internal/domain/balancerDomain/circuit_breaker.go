@@ -0,0 +1,254 @@
+package balancerDomain
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitState состояние автомата circuit breaker
+type CircuitState int32
+
+const (
+	CircuitClosed   CircuitState = iota // запросы проходят, ошибки считаются в скользящем окне
+	CircuitOpen                         // запросы не направляются на бэкенд до истечения cooldown
+	CircuitHalfOpen                     // пропускается ограниченное число пробных запросов
+)
+
+// String возвращает название состояния
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// windowBuckets количество корзин скользящего окна
+const windowBuckets = 10
+
+// Значения настроек circuit breaker по умолчанию
+const (
+	defaultCircuitWindow      = 30 * time.Second
+	defaultCircuitMinRequests = 10
+	defaultCircuitErrorRate   = 0.5
+	defaultCircuitCooldown    = 10 * time.Second
+	defaultCircuitHalfOpen    = 1
+)
+
+// CircuitBreakerSettings содержит пороги срабатывания circuit breaker
+type CircuitBreakerSettings struct {
+	Window           time.Duration // длина скользящего окна статистики
+	MinRequests      int           // минимальное число запросов в окне для принятия решения
+	ErrorRate        float64       // доля ошибок, при которой цепь размыкается
+	SlowThreshold    time.Duration // запрос дольше порога считается медленным (0 — не учитывать)
+	SlowRate         float64       // доля медленных запросов, при которой цепь размыкается (0 — не учитывать)
+	Cooldown         time.Duration // время в состоянии open до перехода в half-open
+	HalfOpenRequests int           // число пробных запросов в состоянии half-open
+}
+
+// windowBucket статистика запросов за один интервал окна
+type windowBucket struct {
+	epoch    int64 // номер интервала, к которому относится корзина
+	total    int
+	failures int
+	slow     int
+}
+
+// CircuitBreaker реализует автомат closed/open/half-open по доле ошибок и медленных запросов
+type CircuitBreaker struct {
+	mu       sync.Mutex
+	settings CircuitBreakerSettings
+	state    CircuitState
+	openedAt time.Time
+	buckets  [windowBuckets]windowBucket
+
+	trials    int // занятые пробные слоты в half-open
+	successes int // успешные пробные запросы в half-open
+}
+
+// NewCircuitBreaker создает circuit breaker, подставляя значения по умолчанию для незаданных настроек
+func NewCircuitBreaker(settings CircuitBreakerSettings) *CircuitBreaker {
+	if settings.Window <= 0 {
+		settings.Window = defaultCircuitWindow
+	}
+	if settings.MinRequests <= 0 {
+		settings.MinRequests = defaultCircuitMinRequests
+	}
+	if settings.ErrorRate <= 0 {
+		settings.ErrorRate = defaultCircuitErrorRate
+	}
+	if settings.Cooldown <= 0 {
+		settings.Cooldown = defaultCircuitCooldown
+	}
+	if settings.HalfOpenRequests <= 0 {
+		settings.HalfOpenRequests = defaultCircuitHalfOpen
+	}
+
+	return &CircuitBreaker{settings: settings}
+}
+
+// State возвращает текущее состояние цепи
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.advance(time.Now())
+	return cb.state
+}
+
+// Ready проверяет, можно ли выбрать бэкенд для нового запроса (без резервирования пробного слота)
+func (cb *CircuitBreaker) Ready() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.advance(time.Now())
+	switch cb.state {
+	case CircuitClosed:
+		return true
+	case CircuitHalfOpen:
+		return cb.trials < cb.settings.HalfOpenRequests
+	default:
+		return false
+	}
+}
+
+// Begin резервирует право на запрос. В состоянии half-open занимает пробный слот
+func (cb *CircuitBreaker) Begin() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.advance(time.Now())
+	switch cb.state {
+	case CircuitClosed:
+		return true
+	case CircuitHalfOpen:
+		if cb.trials >= cb.settings.HalfOpenRequests {
+			return false
+		}
+		cb.trials++
+		return true
+	default:
+		return false
+	}
+}
+
+// Cancel освобождает пробный слот запроса, результат которого не учитывается (например, отмененного клиентом)
+func (cb *CircuitBreaker) Cancel() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == CircuitHalfOpen && cb.trials > 0 {
+		cb.trials--
+	}
+}
+
+// Record учитывает результат запроса и при необходимости переключает состояние
+func (cb *CircuitBreaker) Record(failed bool, latency time.Duration) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	now := time.Now()
+	cb.advance(now)
+
+	slow := cb.settings.SlowThreshold > 0 && latency > cb.settings.SlowThreshold
+
+	switch cb.state {
+	case CircuitHalfOpen:
+		if cb.trials > 0 {
+			cb.trials--
+		}
+		if failed || slow {
+			cb.open(now)
+			return
+		}
+		cb.successes++
+		if cb.successes >= cb.settings.HalfOpenRequests {
+			cb.close()
+		}
+
+	case CircuitClosed:
+		bucket := cb.bucket(now)
+		bucket.total++
+		if failed {
+			bucket.failures++
+		}
+		if slow {
+			bucket.slow++
+		}
+
+		if cb.tripped(now) {
+			cb.open(now)
+		}
+	}
+}
+
+// advance переводит цепь из open в half-open по истечении cooldown
+func (cb *CircuitBreaker) advance(now time.Time) {
+	if cb.state == CircuitOpen && now.Sub(cb.openedAt) >= cb.settings.Cooldown {
+		cb.state = CircuitHalfOpen
+		cb.trials = 0
+		cb.successes = 0
+	}
+}
+
+// open размыкает цепь
+func (cb *CircuitBreaker) open(now time.Time) {
+	cb.state = CircuitOpen
+	cb.openedAt = now
+	cb.trials = 0
+	cb.successes = 0
+}
+
+// close замыкает цепь и сбрасывает накопленную статистику
+func (cb *CircuitBreaker) close() {
+	cb.state = CircuitClosed
+	cb.trials = 0
+	cb.successes = 0
+	cb.buckets = [windowBuckets]windowBucket{}
+}
+
+// bucket возвращает корзину окна для текущего момента, очищая устаревшую
+func (cb *CircuitBreaker) bucket(now time.Time) *windowBucket {
+	epoch := now.UnixNano() / int64(cb.bucketSize())
+	b := &cb.buckets[epoch%windowBuckets]
+	if b.epoch != epoch {
+		*b = windowBucket{epoch: epoch}
+	}
+	return b
+}
+
+// tripped проверяет, превышены ли пороги ошибок или медленных запросов в окне
+func (cb *CircuitBreaker) tripped(now time.Time) bool {
+	current := now.UnixNano() / int64(cb.bucketSize())
+
+	var total, failures, slow int
+	for _, b := range cb.buckets {
+		if current-b.epoch < windowBuckets {
+			total += b.total
+			failures += b.failures
+			slow += b.slow
+		}
+	}
+
+	if total < cb.settings.MinRequests {
+		return false
+	}
+	if float64(failures)/float64(total) >= cb.settings.ErrorRate {
+		return true
+	}
+	return cb.settings.SlowRate > 0 && float64(slow)/float64(total) >= cb.settings.SlowRate
+}
+
+// bucketSize возвращает длительность одной корзины окна
+func (cb *CircuitBreaker) bucketSize() time.Duration {
+	size := cb.settings.Window / windowBuckets
+	if size <= 0 {
+		size = 1
+	}
+	return size
+}
@@ -1,5 +1,7 @@
 package balancerDomain
 
+import "net/http"
+
 // Strategy определяет интерфейс для алгоритмов балансировки нагрузки
 type Strategy interface {
 	NextBackend() *Backend              // возвращает следующий доступный бэкенд
@@ -8,3 +10,84 @@ type Strategy interface {
 	UpdateBackends(backends []*Backend) // обновляет список доступных бэкендов
 	GetBackends() []*Backend            // возвращает список всех бэкендов
 }
+
+// BackendPool определяет стратегию, поддерживающую атомарное изменение пула бэкендов
+type BackendPool interface {
+	ModifyBackends(fn func(current []*Backend) []*Backend) // изменяет пул: fn получает текущий список и возвращает новый
+}
+
+// RoutingContext содержит сведения о входящем запросе, доступные стратегии при выборе бэкенда
+type RoutingContext struct {
+	Request  *http.Request // входящий запрос
+	ClientIP string        // IP-адрес клиента
+	Exclude  []*Backend    // бэкенды, которые нельзя выбирать (например, уже опробованные при повторе)
+}
+
+// IsExcluded проверяет, исключен ли бэкенд из выбора
+func (rc *RoutingContext) IsExcluded(backend *Backend) bool {
+	if rc == nil {
+		return false
+	}
+	for _, b := range rc.Exclude {
+		if b == backend {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed возвращает бэкенды, которые не исключены из выбора
+func (rc *RoutingContext) Allowed(backends []*Backend) []*Backend {
+	if rc == nil || len(rc.Exclude) == 0 {
+		return backends
+	}
+	allowed := make([]*Backend, 0, len(backends))
+	for _, b := range backends {
+		if !rc.IsExcluded(b) {
+			allowed = append(allowed, b)
+		}
+	}
+	return allowed
+}
+
+// RequestStrategy определяет стратегию, выбирающую бэкенд с учетом входящего запроса
+// (по хешу ключа, заголовку, пути и т.п.)
+type RequestStrategy interface {
+	Strategy
+	SelectBackend(rc *RoutingContext) *Backend // возвращает бэкенд для конкретного запроса
+}
+
+// AsRequestStrategy приводит стратегию к RequestStrategy.
+// Стратегии, не учитывающие запрос, оборачиваются адаптером поверх NextBackend
+func AsRequestStrategy(s Strategy) RequestStrategy {
+	if rs, ok := s.(RequestStrategy); ok {
+		return rs
+	}
+	return strategyAdapter{Strategy: s}
+}
+
+// strategyAdapter адаптирует Strategy к интерфейсу RequestStrategy
+type strategyAdapter struct {
+	Strategy
+}
+
+// SelectBackend игнорирует запрос и делегирует выбор в NextBackend.
+// Если стратегия возвращает исключенный бэкенд, выбор повторяется, а затем
+// берется первый доступный из неисключенных. Встроенные стратегии учитывают исключения сами,
+// адаптер нужен только для сторонних реализаций Strategy
+func (a strategyAdapter) SelectBackend(rc *RoutingContext) *Backend {
+	backends := a.GetBackends()
+	for i := 0; i <= len(backends); i++ {
+		backend := a.NextBackend()
+		if backend == nil || !rc.IsExcluded(backend) {
+			return backend
+		}
+	}
+
+	for _, b := range backends {
+		if b.IsAvailable() && !rc.IsExcluded(b) {
+			return b
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package limiter
+
+import "time"
+
+// ClientRecord сохраненное состояние клиента: индивидуальный лимит или отметка об удалении
+type ClientRecord struct {
+	ClientID string
+	Rate     int
+	Period   time.Duration
+	Deleted  bool // клиент удален через API; отметка сохраняется, чтобы удаление пережило перезапуск
+}
+
+// ClientStore определяет интерфейс хранилища клиентов, созданных и измененных через API
+type ClientStore interface {
+	Load() []ClientRecord                                       // возвращает все сохраненные записи
+	Get(clientID string) (ClientRecord, bool)                   // возвращает запись клиента, если она есть
+	Save(clientID string, rate int, period time.Duration) error // сохраняет лимит клиента
+	SaveAll(records []ClientRecord) error                       // сохраняет лимиты нескольких клиентов: все или ни одного
+	Delete(clientID string) error                               // сохраняет отметку об удалении клиента
+	Close() error                                               // освобождает ресурсы хранилища
+}
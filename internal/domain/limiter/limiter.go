@@ -4,7 +4,26 @@ import "time"
 
 // RateLimiter определяет интерфейс для ограничения частоты запросов
 type RateLimiter interface {
-	Allow(key string) bool                                  // проверяет, можно ли пропустить запрос для данного ключа (true - можно пропустить)
+	Allow(key string, policies ...Limit) Decision           // проверяет глобальный лимит, лимит ключа и лимиты политик и списывает запрос, только если его допускают все
 	GetLimit(key string) (int, time.Duration)               //  возвращает текущий лимит для ключа
 	SetLimit(key string, rate int, per time.Duration) error // устанавливает лимит для ключа
 }
+
+// Limit лимит политики: счетчик Key, который создается при первом запросе
+type Limit struct {
+	Key       string        // ключ счетчика, уникальный для политики и значения ее ключа
+	Rate      int           // количество запросов за период
+	Per       time.Duration // период
+	Algorithm string        // алгоритм (пусто — алгоритм по умолчанию)
+}
+
+// Decision результат проверки лимита. Если к запросу применяются несколько лимитов,
+// описывается тот, что отклонил запрос, а для пропущенного запроса — тот, в котором осталось меньше запросов
+type Decision struct {
+	Allowed    bool          // можно ли пропустить запрос
+	Key        string        // ключ счетчика, определившего решение
+	Limit      int           // количество запросов за период (0 — лимиты к ключу не применяются)
+	Remaining  int           // сколько запросов еще можно сделать сразу
+	Reset      time.Duration // через сколько лимит восстановится полностью
+	RetryAfter time.Duration // через сколько станет доступен следующий запрос (0 — доступен сейчас)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"CloudCamp/pkg/utils"
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// principalKey ключ контекста, под которым хранится субъект запроса к admin API
+type principalKey struct{}
+
+// AdminAuthMiddleware middleware аутентификации запросов к управляющим маршрутам.
+// Проверяет bearer-токен, если он задан; клиентский сертификат проверяется на уровне TLS
+type AdminAuthMiddleware struct {
+	token atomic.Pointer[string]
+}
+
+// NewAdminAuthMiddleware создает middleware аутентификации с указанным токеном
+func NewAdminAuthMiddleware(token string) *AdminAuthMiddleware {
+	m := &AdminAuthMiddleware{}
+	m.SetToken(token)
+	return m
+}
+
+// SetToken заменяет токен для последующих запросов
+func (m *AdminAuthMiddleware) SetToken(token string) {
+	m.token.Store(&token)
+}
+
+// Middleware возвращает HTTP middleware, отклоняющий запросы без корректных учетных данных
+func (m *AdminAuthMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := "local"
+
+		// Клиентский сертификат уже проверен TLS-рукопожатием
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+			principal = "cert:" + r.TLS.VerifiedChains[0][0].Subject.CommonName
+		}
+
+		if token := *m.token.Load(); token != "" {
+			if !validBearer(r.Header.Get("Authorization"), token) {
+				slog.Warn("admin request unauthorized",
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("remote_addr", r.RemoteAddr),
+				)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				utils.SendJSON(w,
+					http.StatusUnauthorized,
+					"Unauthorized",
+				)
+				return
+			}
+			if principal == "local" {
+				principal = "token"
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	})
+}
+
+// Principal возвращает субъект запроса, установленный AdminAuthMiddleware
+func Principal(r *http.Request) string {
+	if principal, ok := r.Context().Value(principalKey{}).(string); ok {
+		return principal
+	}
+	return ""
+}
+
+// validBearer сравнивает bearer-токен из заголовка Authorization с ожидаемым за постоянное время
+func validBearer(header, token string) bool {
+	const prefix = "bearer "
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(header[len(prefix):]), []byte(token)) == 1
+}
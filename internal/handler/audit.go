@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// maxAuditBody максимальный размер тела запроса, сохраняемого в журнале аудита
+const maxAuditBody = 4 << 10
+
+// AuditMiddleware middleware, записывающий в журнал аудита каждый изменяющий запрос к admin API
+type AuditMiddleware struct {
+	logger *slog.Logger
+}
+
+// NewAuditMiddleware создает middleware аудита, пишущий записи в logger
+func NewAuditMiddleware(logger *slog.Logger) *AuditMiddleware {
+	return &AuditMiddleware{logger: logger}
+}
+
+// Middleware возвращает HTTP middleware аудита. Запросы на чтение (GET, HEAD) не записываются
+func (m *AuditMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Сохраняем начало тела для журнала и возвращаем его обработчику целиком
+		var body []byte
+		if r.Body != nil {
+			body, _ = io.ReadAll(io.LimitReader(r.Body, maxAuditBody))
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+
+		m.logger.Info("admin change",
+			slog.String("principal", Principal(r)),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("query", r.URL.RawQuery),
+			slog.String("body", string(body)),
+			slog.Int("status", rec.status),
+			slog.Duration("duration", time.Since(start)),
+		)
+	})
+}
+
+// statusRecorder запоминает код ответа обработчика
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader запоминает код ответа и передает его дальше
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap позволяет http.ResponseController добраться до исходного ResponseWriter
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"CloudCamp/internal/domain/balancerDomain"
+	"CloudCamp/internal/limiter"
+	"CloudCamp/internal/metrics"
+	"CloudCamp/pkg/utils"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// nextBackend выбирает бэкенд и резервирует его под запрос: занимает место в лимите одновременных
+// запросов бэкенда и проверяет circuit breaker. Если свободного бэкенда нет, первая попытка запроса
+// ждет в очереди своего класса приоритета. Повторные попытки не ждут: опробованные бэкенды исключены,
+// и ожидание может не закончиться ничем, кроме таймаута. Второе значение равно true, если встретился занятый бэкенд
+func (h *ProxyHandler) nextBackend(r *http.Request, rc *balancerDomain.RoutingContext) (*balancerDomain.Backend, bool) {
+	var (
+		backend *balancerDomain.Backend
+		busy    bool
+	)
+	try := func() bool {
+		var saturated bool
+		backend, saturated = h.selectBackend(rc)
+		busy = busy || saturated
+		return backend != nil
+	}
+
+	// Исключенные бэкенды появляются только после первой попытки
+	queue := h.queue.Load()
+	if queue == nil || len(rc.Exclude) > 0 {
+		try()
+		return backend, busy
+	}
+
+	// Класс определяется по тому же ID клиента, что и лимит одновременных запросов. Заголовок приоритета
+	// может подставить любой клиент, поэтому он учитывается только в запросах от доверенных прокси
+	clientID := utils.TrustedClientID(r)
+	var header http.Header
+	if utils.FromTrustedProxy(r) {
+		header = r.Header
+	}
+	class := queue.Class(clientID, header)
+
+	if err := queue.Wait(r.Context(), class, try); err != nil {
+		if errors.Is(err, limiter.ErrQueueFull) {
+			slog.Warn("admission queue is full",
+				slog.String("client_id", clientID),
+				slog.String("class", class),
+			)
+		}
+		return nil, busy
+	}
+	return backend, false
+}
+
+// selectBackend выбирает бэкенд, пропуская занятые и те, которые не пропускает circuit breaker
+// (например, из-за занятых пробных слотов). Пропущенные бэкенды не остаются в rc.Exclude, чтобы участвовать
+// в следующем выборе, в том числе при повторной проверке из очереди: исключаются только опробованные.
+// Второе значение равно true, если встретился занятый бэкенд
+func (h *ProxyHandler) selectBackend(rc *balancerDomain.RoutingContext) (*balancerDomain.Backend, bool) {
+	var (
+		skipped []*balancerDomain.Backend
+		busy    bool
+	)
+	defer func() {
+		if len(skipped) > 0 {
+			rc.Exclude = slices.DeleteFunc(rc.Exclude, func(b *balancerDomain.Backend) bool {
+				return slices.Contains(skipped, b)
+			})
+		}
+	}()
+
+	for {
+		backend := h.balancer.SelectBackend(rc)
+		if backend == nil {
+			return nil, busy
+		}
+		rc.Exclude = append(rc.Exclude, backend)
+
+		if !backend.TryIncrementConnections() {
+			skipped = append(skipped, backend)
+			busy = true
+			continue
+		}
+		if !backend.AllowRequest() {
+			// Место освобождается без оповещения очереди: selectBackend может выполняться под ее мьютексом
+			backend.DecrementConnections()
+			skipped = append(skipped, backend)
+			continue
+		}
+		return backend, false
+	}
+}
+
+// release освобождает место запроса на бэкенде и передает его ожидающим в очереди запросам
+func (h *ProxyHandler) release(backend *balancerDomain.Backend) {
+	backend.DecrementConnections()
+	if queue := h.queue.Load(); queue != nil {
+		queue.Notify()
+	}
+}
+
+// reportResult учитывает результат попытки в пассивной проверке здоровья, circuit breaker и адаптивном лимите.
+// Если запрос отменен клиентом (отключение, таймаут), результат ничего не говорит о бэкенде и не учитывается,
+// но пробный слот circuit breaker освобождается, иначе цепь в half-open не дождется результата
+func (h *ProxyHandler) reportResult(r *http.Request, backend *balancerDomain.Backend, resp *http.Response, err error, latency time.Duration) {
+	if r.Context().Err() != nil {
+		backend.AbortRequest()
+		return
+	}
+
+	backend.RecordResult(err != nil || resp.StatusCode >= http.StatusInternalServerError, latency)
+	if err != nil {
+		// Исключаем бэкенд только после нескольких неудач подряд
+		if backend.ReportFailure() {
+			slog.Warn("backend marked down", slog.String("backend", backend.URL))
+		}
+		return
+	}
+	backend.ReportSuccess()
+}
+
+// sendNoBackend отвечает 503, если для запроса не нашлось бэкенда
+func (h *ProxyHandler) sendNoBackend(w http.ResponseWriter, r *http.Request, busy bool) {
+	metrics.ObserveProxy(metrics.NoBackend, r.Method, http.StatusServiceUnavailable, 0)
+
+	if busy {
+		slog.Warn("all backends are busy")
+		metrics.ObserveConcurrencyRejected(metrics.ConcurrencyBackend)
+		utils.SendJSON(w,
+			http.StatusServiceUnavailable,
+			"All backends are busy",
+		)
+		return
+	}
+
+	slog.Warn("No backend available")
+	utils.SendJSON(w,
+		http.StatusServiceUnavailable,
+		"No backend available",
+	)
+}
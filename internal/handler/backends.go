@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"CloudCamp/internal/balancer"
+	"CloudCamp/internal/config"
+	"CloudCamp/internal/domain/balancerDomain"
+	"CloudCamp/pkg/utils"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// backendsPath префикс маршрутов управления бэкендами
+const backendsPath = "/admin/backends"
+
+// BackendHandler обработчик admin API для управления пулом бэкендов во время работы
+type BackendHandler struct {
+	mu       sync.Mutex // сериализует изменения пула, если стратегия не умеет делать это сама
+	balancer balancerDomain.Strategy
+	cfg      atomic.Pointer[config.Config]
+}
+
+// BackendRequest структура запроса на добавление бэкенда
+type BackendRequest struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// BackendResponse представление бэкенда в ответах admin API
+type BackendResponse struct {
+	ID                string `json:"id"`
+	URL               string `json:"url"`
+	Weight            int    `json:"weight"`
+	Alive             bool   `json:"alive"`
+	Draining          bool   `json:"draining"`
+	ActiveConnections int64  `json:"active_connections"`
+	MaxConnections    int    `json:"max_connections,omitempty"` // действующий лимит с учетом адаптивного
+	AdaptiveLimit     int    `json:"adaptive_limit,omitempty"`
+	Circuit           string `json:"circuit,omitempty"`
+}
+
+// NewBackendHandler создает новый обработчик для управления бэкендами
+func NewBackendHandler(balancer balancerDomain.Strategy, cfg *config.Config) *BackendHandler {
+	h := &BackendHandler{
+		balancer: balancer,
+	}
+	h.cfg.Store(cfg)
+
+	return h
+}
+
+// UpdateConfig задает конфигурацию, по которой создаются новые бэкенды
+func (h *BackendHandler) UpdateConfig(cfg *config.Config) {
+	h.cfg.Store(cfg)
+}
+
+// ServeHTTP разбирает путь и направляет запрос в нужный обработчик:
+// GET/POST /admin/backends, DELETE /admin/backends/{id}, POST/DELETE /admin/backends/{id}/drain
+func (h *BackendHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, backendsPath), "/")
+	parts := strings.Split(rest, "/")
+
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		h.ListBackends(w, r)
+	case rest == "" && r.Method == http.MethodPost:
+		h.AddBackend(w, r)
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		h.RemoveBackend(w, r, parts[0])
+	case len(parts) == 2 && parts[1] == "drain" && r.Method == http.MethodPost:
+		h.DrainBackend(w, r, parts[0])
+	case len(parts) == 2 && parts[1] == "drain" && r.Method == http.MethodDelete:
+		h.UndrainBackend(w, r, parts[0])
+	case rest == "" || len(parts) == 1 || (len(parts) == 2 && parts[1] == "drain"):
+		utils.SendJSON(w,
+			http.StatusMethodNotAllowed,
+			"Method not allowed",
+		)
+	default:
+		utils.SendJSON(w,
+			http.StatusNotFound,
+			"Not found",
+		)
+	}
+}
+
+// ListBackends возвращает список бэкендов с их состоянием
+func (h *BackendHandler) ListBackends(w http.ResponseWriter, _ *http.Request) {
+	backends := h.balancer.GetBackends()
+
+	resp := make([]BackendResponse, 0, len(backends))
+	for _, b := range backends {
+		resp = append(resp, newBackendResponse(b))
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// AddBackend добавляет бэкенд в пул
+func (h *BackendHandler) AddBackend(w http.ResponseWriter, r *http.Request) {
+	var req BackendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Error("Error decoding request", slog.String("error", err.Error()))
+		utils.SendJSON(w,
+			http.StatusBadRequest,
+			"Invalid request body",
+		)
+		return
+	}
+
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		slog.Warn("Invalid backend URL", slog.String("url", req.URL))
+		utils.SendJSON(w,
+			http.StatusBadRequest,
+			"Invalid backend URL. Example: 'http://backend4:8084'",
+		)
+		return
+	}
+	if req.Weight < 0 {
+		utils.SendJSON(w,
+			http.StatusBadRequest,
+			"Weight must not be negative",
+		)
+		return
+	}
+
+	backend := balancer.NewBackendFromConfig(h.cfg.Load(), config.BackendConfig{
+		ID:     req.ID,
+		URL:    req.URL,
+		Weight: req.Weight,
+	})
+
+	exists := false
+	h.modifyBackends(func(current []*balancerDomain.Backend) []*balancerDomain.Backend {
+		for _, b := range current {
+			if b.ID == backend.ID || b.URL == backend.URL {
+				exists = true
+				return current
+			}
+		}
+
+		updated := make([]*balancerDomain.Backend, 0, len(current)+1)
+		updated = append(updated, current...)
+		return append(updated, backend)
+	})
+
+	if exists {
+		utils.SendJSON(w,
+			http.StatusConflict,
+			"Backend already exists",
+		)
+		return
+	}
+
+	slog.Info("backend added", slog.String("id", backend.ID), slog.String("url", backend.URL))
+	utils.WriteJSON(w, http.StatusCreated, newBackendResponse(backend))
+}
+
+// RemoveBackend удаляет бэкенд из пула. Уже начатые запросы к нему завершаются штатно
+func (h *BackendHandler) RemoveBackend(w http.ResponseWriter, _ *http.Request, id string) {
+	var removed *balancerDomain.Backend
+	h.modifyBackends(func(current []*balancerDomain.Backend) []*balancerDomain.Backend {
+		updated := make([]*balancerDomain.Backend, 0, len(current))
+		for _, b := range current {
+			if b.ID == id {
+				removed = b
+				continue
+			}
+			updated = append(updated, b)
+		}
+		return updated
+	})
+
+	if removed == nil {
+		utils.SendJSON(w,
+			http.StatusNotFound,
+			"Backend not found",
+		)
+		return
+	}
+
+	slog.Info("backend removed", slog.String("id", removed.ID), slog.String("url", removed.URL))
+	utils.WriteJSON(w, http.StatusOK, newBackendResponse(removed))
+}
+
+// DrainBackend выводит бэкенд из ротации: новые запросы на него не направляются,
+// а текущие завершаются штатно. С параметром wait (например, ?wait=30s) ожидает
+// завершения активных соединений. Возвращает 200, если соединений не осталось, иначе 202
+func (h *BackendHandler) DrainBackend(w http.ResponseWriter, r *http.Request, id string) {
+	backend := h.findBackend(id)
+	if backend == nil {
+		utils.SendJSON(w,
+			http.StatusNotFound,
+			"Backend not found",
+		)
+		return
+	}
+
+	var wait time.Duration
+	if v := r.URL.Query().Get("wait"); v != "" {
+		var err error
+		if wait, err = time.ParseDuration(v); err != nil || wait < 0 {
+			utils.SendJSON(w,
+				http.StatusBadRequest,
+				"Invalid wait format. Example: '1s', '500ms', '2m'",
+			)
+			return
+		}
+	}
+
+	backend.SetDraining(true)
+	slog.Info("backend draining",
+		slog.String("id", backend.ID),
+		slog.Int64("active_connections", backend.GetActiveConnections()),
+	)
+
+	if wait > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), wait)
+		defer cancel()
+		if err := backend.WaitDrained(ctx); err != nil {
+			slog.Warn("backend drain timed out",
+				slog.String("id", backend.ID),
+				slog.Int64("active_connections", backend.GetActiveConnections()),
+			)
+		}
+	}
+
+	code := http.StatusOK
+	if backend.GetActiveConnections() > 0 {
+		code = http.StatusAccepted
+	}
+	utils.WriteJSON(w, code, newBackendResponse(backend))
+}
+
+// UndrainBackend возвращает выведенный бэкенд в ротацию
+func (h *BackendHandler) UndrainBackend(w http.ResponseWriter, _ *http.Request, id string) {
+	backend := h.findBackend(id)
+	if backend == nil {
+		utils.SendJSON(w,
+			http.StatusNotFound,
+			"Backend not found",
+		)
+		return
+	}
+
+	backend.SetDraining(false)
+
+	slog.Info("backend returned to rotation", slog.String("id", backend.ID))
+	utils.WriteJSON(w, http.StatusOK, newBackendResponse(backend))
+}
+
+// modifyBackends атомарно изменяет пул бэкендов
+func (h *BackendHandler) modifyBackends(fn func(current []*balancerDomain.Backend) []*balancerDomain.Backend) {
+	if pool, ok := h.balancer.(balancerDomain.BackendPool); ok {
+		pool.ModifyBackends(fn)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.balancer.UpdateBackends(fn(h.balancer.GetBackends()))
+}
+
+// findBackend ищет бэкенд по идентификатору
+func (h *BackendHandler) findBackend(id string) *balancerDomain.Backend {
+	for _, b := range h.balancer.GetBackends() {
+		if b.ID == id {
+			return b
+		}
+	}
+	return nil
+}
+
+// newBackendResponse формирует представление бэкенда для ответа
+func newBackendResponse(b *balancerDomain.Backend) BackendResponse {
+	resp := BackendResponse{
+		ID:                b.ID,
+		URL:               b.URL,
+		Weight:            b.Weight,
+		Alive:             b.IsAlive(),
+		Draining:          b.IsDraining(),
+		ActiveConnections: b.GetActiveConnections(),
+		MaxConnections:    b.MaxConnections(),
+	}
+	if l := b.AdaptiveLimit(); l != nil {
+		resp.AdaptiveLimit = l.Limit()
+	}
+	if cb := b.CircuitBreaker(); cb != nil {
+		resp.Circuit = cb.State().String()
+	}
+	return resp
+}
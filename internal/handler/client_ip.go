@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"CloudCamp/pkg/utils"
+	"net/http"
+	"sync/atomic"
+)
+
+// ClientIPMiddleware middleware, определяющий IP-адрес клиента с учетом доверенных прокси.
+// Адрес сохраняется в контексте запроса и доступен последующим обработчикам через utils.ClientIP
+type ClientIPMiddleware struct {
+	resolver atomic.Pointer[utils.ClientIPResolver]
+}
+
+// NewClientIPMiddleware создает middleware с указанным resolver
+func NewClientIPMiddleware(resolver *utils.ClientIPResolver) *ClientIPMiddleware {
+	m := &ClientIPMiddleware{}
+	m.SetResolver(resolver)
+	return m
+}
+
+// SetResolver заменяет resolver (список доверенных прокси) для последующих запросов
+func (m *ClientIPMiddleware) SetResolver(resolver *utils.ClientIPResolver) {
+	m.resolver.Store(resolver)
+}
+
+// Middleware возвращает HTTP middleware, определяющий IP-адрес клиента
+func (m *ClientIPMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resolver := m.resolver.Load()
+		next.ServeHTTP(w, utils.WithClientIP(r, resolver.Resolve(r), resolver.TrustedPeer(r)))
+	})
+}
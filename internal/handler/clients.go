@@ -1,17 +1,39 @@
 package handler
 
 import (
+	limiterDomain "CloudCamp/internal/domain/limiter"
 	"CloudCamp/internal/limiter"
 	"CloudCamp/pkg/utils"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+// clientsPath префикс маршрутов управления клиентами
+const clientsPath = "/clients"
+
+// Параметры постраничного вывода списка клиентов
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// maxImportBody максимальный размер тела запроса массового импорта
+const maxImportBody = 8 << 20
+
+// globalClientID зарезервированный ключ глобального лимита
+const globalClientID = "global"
+
 // ClientHandler обработчик для управления клиентами
 type ClientHandler struct {
+	mu      sync.Mutex // сериализует изменения, чтобы проверка существования и запись были атомарными
 	limiter *limiter.MemoryRateLimiter
+	store   limiterDomain.ClientStore // хранилище изменений, nil — изменения не сохраняются
 }
 
 // ClientRequest структура для запроса на создание/обновление клиента
@@ -21,73 +43,328 @@ type ClientRequest struct {
 	Period   string `json:"period"`
 }
 
-// NewClientHandler создает новый обработчик для клиентов
-func NewClientHandler(limiter *limiter.MemoryRateLimiter) *ClientHandler {
-	return &ClientHandler{limiter: limiter}
+// ClientPatchRequest структура для частичного обновления клиента: незаданные поля не меняются
+type ClientPatchRequest struct {
+	Rate   *int    `json:"rate"`
+	Period *string `json:"period"`
+}
+
+// ClientResponse представление клиента и состояния его бакета в ответах API
+type ClientResponse struct {
+	ClientID   string    `json:"client_id"`
+	Rate       int       `json:"rate"`
+	Period     string    `json:"period"`
+	Algorithm  string    `json:"algorithm"`   // алгоритм лимита
+	Tokens     int       `json:"tokens"`      // доступные токены на момент запроса (целая часть)
+	LastRefill time.Time `json:"last_refill"` // время последнего пополнения бакета
+}
+
+// ClientListResponse страница списка клиентов
+type ClientListResponse struct {
+	Clients []ClientResponse `json:"clients"`
+	Total   int              `json:"total"`
+	Offset  int              `json:"offset"`
+	Limit   int              `json:"limit"`
+}
+
+// ClientImportResponse результат массового импорта клиентов
+type ClientImportResponse struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
+
+// ClientImportError проблема в одной записи массового импорта
+type ClientImportError struct {
+	Index    int    `json:"index"`
+	ClientID string `json:"client_id"`
+	Error    string `json:"error"`
+}
+
+// ClientImportErrorResponse ответ на импорт с некорректными записями
+type ClientImportErrorResponse struct {
+	Code    int                 `json:"code"`
+	Message string              `json:"message"`
+	Errors  []ClientImportError `json:"errors"`
+}
+
+// NewClientHandler создает новый обработчик для клиентов. Если store задан,
+// каждое изменение сначала сохраняется в нем и только затем применяется
+func NewClientHandler(limiter *limiter.MemoryRateLimiter, store limiterDomain.ClientStore) *ClientHandler {
+	return &ClientHandler{limiter: limiter, store: store}
+}
+
+// ServeHTTP разбирает путь и направляет запрос в нужный обработчик:
+// GET/POST/DELETE /clients, POST /clients/import, GET/PUT/PATCH/DELETE /clients/{id}
+func (h *ClientHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, clientsPath), "/")
+
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		h.ListClients(w, r)
+	case rest == "" && r.Method == http.MethodPost:
+		h.CreateClient(w, r)
+	case rest == "" && r.Method == http.MethodDelete:
+		h.DeleteClient(w, r)
+	case rest == "import" && r.Method == http.MethodPost:
+		h.ImportClients(w, r)
+	case rest != "" && !strings.Contains(rest, "/"):
+		switch r.Method {
+		case http.MethodGet:
+			h.GetClient(w, r, rest)
+		case http.MethodPut:
+			h.ReplaceClient(w, r, rest)
+		case http.MethodPatch:
+			h.PatchClient(w, r, rest)
+		case http.MethodDelete:
+			h.RemoveClient(w, r, rest)
+		default:
+			h.methodNotAllowed(w, r)
+		}
+	case rest == "":
+		h.methodNotAllowed(w, r)
+	default:
+		utils.SendJSON(w,
+			http.StatusNotFound,
+			"Not found",
+		)
+	}
+}
+
+// ListClients возвращает страницу клиентов с индивидуальными лимитами, упорядоченных по идентификатору.
+// Параметры: offset (по умолчанию 0) и limit (по умолчанию 100, не более 1000)
+func (h *ClientHandler) ListClients(w http.ResponseWriter, r *http.Request) {
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		utils.SendJSON(w,
+			http.StatusBadRequest,
+			"Invalid offset",
+		)
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultPageLimit)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		utils.SendJSON(w,
+			http.StatusBadRequest,
+			fmt.Sprintf("Invalid limit. Must be between 1 and %d", maxPageLimit),
+		)
+		return
+	}
+
+	clients := h.limiter.Clients()
+	// Смещение ограничивается заранее: offset+limit может переполниться
+	start := min(offset, len(clients))
+	page := clients[start : start+min(limit, len(clients)-start)]
+
+	resp := ClientListResponse{
+		Clients: make([]ClientResponse, 0, len(page)),
+		Total:   len(clients),
+		Offset:  offset,
+		Limit:   limit,
+	}
+	for _, c := range page {
+		resp.Clients = append(resp.Clients, newClientResponse(c))
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+// GetClient возвращает настройки клиента и текущее состояние его бакета
+func (h *ClientHandler) GetClient(w http.ResponseWriter, _ *http.Request, clientID string) {
+	state, ok := h.limiter.Client(clientID)
+	if !ok {
+		h.clientNotFound(w)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, newClientResponse(state))
 }
 
 // CreateClient обработчик для создания нового клиента
 func (h *ClientHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		slog.Error("Method not allowed", slog.String("method", r.Method))
-		utils.SendJSON(w,
-			http.StatusMethodNotAllowed,
-			"Method not allowed",
-		)
+		h.methodNotAllowed(w, r)
 		return
 	}
 
 	var req ClientRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		slog.Error("Error decoding request", slog.String("error", err.Error()))
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	period, msg := validateClient(req.ClientID, req.Rate, req.Period)
+	if msg != "" {
+		slog.Warn("Invalid request", slog.String("client_id", req.ClientID), slog.String("error", msg))
 		utils.SendJSON(w,
 			http.StatusBadRequest,
-			"Invalid request body",
+			msg,
 		)
 		return
 	}
 
-	period, err := time.ParseDuration(req.Period)
-	if err != nil {
-		slog.Error("Error parsing period", slog.String("period", req.Period), slog.String("error", err.Error()))
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, exists := h.limiter.Client(req.ClientID); exists {
+		utils.SendJSON(w,
+			http.StatusConflict,
+			"Client already exists",
+		)
+		return
+	}
+
+	h.setClient(w, http.StatusCreated, req.ClientID, req.Rate, period)
+}
+
+// ReplaceClient задает все настройки клиента, создавая его при отсутствии
+func (h *ClientHandler) ReplaceClient(w http.ResponseWriter, r *http.Request, clientID string) {
+	var req ClientRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if req.ClientID != "" && req.ClientID != clientID {
 		utils.SendJSON(w,
 			http.StatusBadRequest,
-			"Invalid period format. Example: '1s', '500ms', '2m'",
+			"Client ID in body does not match path",
 		)
 		return
 	}
 
-	if req.ClientID == "" || req.Rate <= 0 || period <= 0 {
-		slog.Warn("Invalid request", slog.String("client_id", req.ClientID))
+	period, msg := validateClient(clientID, req.Rate, req.Period)
+	if msg != "" {
 		utils.SendJSON(w,
 			http.StatusBadRequest,
-			"Invalid client parameters",
+			msg,
 		)
 		return
 	}
 
-	// Устанавливаем лимит для клиента
-	err = h.limiter.SetClientLimit(req.ClientID, req.Rate, period)
-	if err != nil {
-		slog.Error("Error setting client limit", slog.String("error", err.Error()))
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	code := http.StatusOK
+	if _, exists := h.limiter.Client(clientID); !exists {
+		code = http.StatusCreated
+	}
+
+	h.setClient(w, code, clientID, req.Rate, period)
+}
+
+// PatchClient изменяет только переданные настройки существующего клиента
+func (h *ClientHandler) PatchClient(w http.ResponseWriter, r *http.Request, clientID string) {
+	var req ClientPatchRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state, exists := h.limiter.Client(clientID)
+	if !exists {
+		h.clientNotFound(w)
+		return
+	}
+
+	rate, period := state.Rate, state.Per.String()
+	if req.Rate != nil {
+		rate = *req.Rate
+	}
+	if req.Period != nil {
+		period = *req.Period
+	}
+
+	per, msg := validateClient(clientID, rate, period)
+	if msg != "" {
 		utils.SendJSON(w,
-			http.StatusInternalServerError,
-			"Failed to set client limit",
+			http.StatusBadRequest,
+			msg,
 		)
 		return
 	}
 
-	utils.SendJSON(w, http.StatusCreated, "Client created successfully")
+	h.setClient(w, http.StatusOK, clientID, rate, per)
+}
+
+// ImportClients создает или обновляет клиентов из массива. Импорт атомарен: при ошибке в любой записи
+// не применяется ни одна, а в ответе перечисляются все проблемы. Записи сохраняются в хранилище одним пакетом
+// до применения, поэтому ошибка хранилища тоже не оставляет импорт примененным частично
+func (h *ClientHandler) ImportClients(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBody)
+
+	var reqs []ClientRequest
+	if !decodeRequest(w, r, &reqs) {
+		return
+	}
+
+	periods := make([]time.Duration, len(reqs))
+	seen := make(map[string]bool, len(reqs))
+	var problems []ClientImportError
+	for i, req := range reqs {
+		period, msg := validateClient(req.ClientID, req.Rate, req.Period)
+		if msg == "" && seen[req.ClientID] {
+			msg = "Duplicate client ID"
+		}
+		if msg != "" {
+			problems = append(problems, ClientImportError{Index: i, ClientID: req.ClientID, Error: msg})
+			continue
+		}
+		seen[req.ClientID] = true
+		periods[i] = period
+	}
+
+	if len(problems) > 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, ClientImportErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid clients",
+			Errors:  problems,
+		})
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.store != nil {
+		records := make([]limiterDomain.ClientRecord, len(reqs))
+		for i, req := range reqs {
+			records[i] = limiterDomain.ClientRecord{ClientID: req.ClientID, Rate: req.Rate, Period: periods[i]}
+		}
+		if err := h.store.SaveAll(records); err != nil {
+			slog.Error("Error saving clients", slog.Int("count", len(records)), slog.String("error", err.Error()))
+			utils.SendJSON(w,
+				http.StatusInternalServerError,
+				"Failed to save clients",
+			)
+			return
+		}
+	}
+
+	var resp ClientImportResponse
+	for i, req := range reqs {
+		if _, exists := h.limiter.Client(req.ClientID); exists {
+			resp.Updated++
+		} else {
+			resp.Created++
+		}
+
+		if err := h.limiter.SetClientLimit(req.ClientID, req.Rate, periods[i]); err != nil {
+			slog.Error("Error setting client limit", slog.String("client_id", req.ClientID), slog.String("error", err.Error()))
+			utils.SendJSON(w,
+				http.StatusInternalServerError,
+				"Failed to set client limit",
+			)
+			return
+		}
+	}
+
+	slog.Info("clients imported", slog.Int("created", resp.Created), slog.Int("updated", resp.Updated))
+	utils.WriteJSON(w, http.StatusOK, resp)
 }
 
-// DeleteClient обработчик для удаления клиента
+// DeleteClient обработчик для удаления клиента по параметру client_id
 func (h *ClientHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
-		slog.Error("Method not allowed", slog.String("method", r.Method))
-		utils.SendJSON(w,
-			http.StatusMethodNotAllowed,
-			"Method not allowed",
-		)
+		h.methodNotAllowed(w, r)
 		return
 	}
 
@@ -101,7 +378,138 @@ func (h *ClientHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.RemoveClient(w, r, clientID)
+}
+
+// RemoveClient удаляет индивидуальный лимит клиента и возвращает его последнее состояние
+func (h *ClientHandler) RemoveClient(w http.ResponseWriter, _ *http.Request, clientID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state, exists := h.limiter.Client(clientID)
+	if !exists {
+		h.clientNotFound(w)
+		return
+	}
+
+	if h.store != nil {
+		if err := h.store.Delete(clientID); err != nil {
+			slog.Error("Error saving client", slog.String("client_id", clientID), slog.String("error", err.Error()))
+			utils.SendJSON(w,
+				http.StatusInternalServerError,
+				"Failed to save client",
+			)
+			return
+		}
+	}
+
 	// Удаляем настройки клиента
 	h.limiter.RemoveClientLimit(clientID)
-	utils.SendJSON(w, http.StatusOK, "Client deleted successfully")
+	slog.Info("client deleted", slog.String("client_id", clientID))
+	utils.WriteJSON(w, http.StatusOK, newClientResponse(state))
+}
+
+// setClient устанавливает лимит клиента и отвечает его актуальным состоянием (вызывается под mu)
+func (h *ClientHandler) setClient(w http.ResponseWriter, code int, clientID string, rate int, period time.Duration) {
+	if err := h.save(clientID, rate, period); err != nil {
+		slog.Error("Error saving client", slog.String("client_id", clientID), slog.String("error", err.Error()))
+		utils.SendJSON(w,
+			http.StatusInternalServerError,
+			"Failed to save client",
+		)
+		return
+	}
+
+	if err := h.limiter.SetClientLimit(clientID, rate, period); err != nil {
+		slog.Error("Error setting client limit", slog.String("error", err.Error()))
+		utils.SendJSON(w,
+			http.StatusInternalServerError,
+			"Failed to set client limit",
+		)
+		return
+	}
+
+	state, _ := h.limiter.Client(clientID)
+	slog.Info("client limit set", slog.String("client_id", clientID), slog.Int("rate", rate), slog.Duration("period", period))
+	utils.WriteJSON(w, code, newClientResponse(state))
+}
+
+// save сохраняет лимит клиента в хранилище, если оно задано
+func (h *ClientHandler) save(clientID string, rate int, period time.Duration) error {
+	if h.store == nil {
+		return nil
+	}
+	return h.store.Save(clientID, rate, period)
+}
+
+// methodNotAllowed отвечает 405 на неподдерживаемый метод
+func (h *ClientHandler) methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	slog.Error("Method not allowed", slog.String("method", r.Method))
+	utils.SendJSON(w,
+		http.StatusMethodNotAllowed,
+		"Method not allowed",
+	)
+}
+
+// clientNotFound отвечает 404 на запрос к несуществующему клиенту
+func (h *ClientHandler) clientNotFound(w http.ResponseWriter) {
+	utils.SendJSON(w,
+		http.StatusNotFound,
+		"Client not found",
+	)
+}
+
+// validateClient проверяет параметры клиента и возвращает разобранный период
+// или сообщение об ошибке для ответа
+func validateClient(clientID string, rate int, period string) (time.Duration, string) {
+	if clientID == "" {
+		return 0, "Client ID is required"
+	}
+	if clientID == globalClientID {
+		return 0, fmt.Sprintf("Client ID %q is reserved", globalClientID)
+	}
+
+	per, err := time.ParseDuration(period)
+	if err != nil {
+		return 0, "Invalid period format. Example: '1s', '500ms', '2m'"
+	}
+	if rate <= 0 || per <= 0 {
+		return 0, "Invalid client parameters"
+	}
+
+	return per, ""
+}
+
+// decodeRequest разбирает JSON-тело запроса и при ошибке отвечает 400
+func decodeRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		slog.Error("Error decoding request", slog.String("error", err.Error()))
+		utils.SendJSON(w,
+			http.StatusBadRequest,
+			"Invalid request body",
+		)
+		return false
+	}
+	return true
+}
+
+// queryInt возвращает целочисленный параметр запроса или значение по умолчанию
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// newClientResponse формирует представление клиента для ответа
+func newClientResponse(state limiter.BucketState) ClientResponse {
+	return ClientResponse{
+		ClientID:   state.Key,
+		Rate:       state.Rate,
+		Period:     state.Per.String(),
+		Algorithm:  state.Algorithm,
+		Tokens:     int(state.Tokens),
+		LastRefill: state.Last,
+	}
 }
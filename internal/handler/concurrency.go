@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"CloudCamp/internal/limiter"
+	"CloudCamp/internal/metrics"
+	"CloudCamp/pkg/utils"
+	"log/slog"
+	"net/http"
+)
+
+// ConcurrencyMiddleware middleware, ограничивающий число одновременных запросов одного клиента.
+// Клиент определяется по API-ключу или JWT, проверенным rate limiter, иначе по IP-адресу:
+// произвольный X-Client-ID позволил бы обойти лимит, меняя значение на каждом соединении
+type ConcurrencyMiddleware struct {
+	limiter *limiter.ConcurrencyLimiter
+}
+
+// NewConcurrencyMiddleware создает middleware ограничения одновременных запросов
+func NewConcurrencyMiddleware(limiter *limiter.ConcurrencyLimiter) *ConcurrencyMiddleware {
+	return &ConcurrencyMiddleware{
+		limiter: limiter,
+	}
+}
+
+// Middleware возвращает HTTP middleware, который занимает место клиента на время обработки запроса.
+// Если место не освободилось за queue_timeout, запрос отклоняется с кодом 429
+func (m *ConcurrencyMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID := utils.TrustedClientID(r)
+
+		release, ok := m.limiter.Acquire(r.Context(), clientID)
+		if !ok {
+			metrics.ObserveConcurrencyRejected(metrics.ConcurrencyClient)
+			slog.Warn("concurrency limit exceeded",
+				slog.String("client_id", clientID),
+				slog.Int("in_flight", m.limiter.InFlight(clientID)),
+			)
+			utils.SendJSON(w,
+				http.StatusTooManyRequests,
+				"Too Many Concurrent Requests",
+			)
+			return
+		}
+		defer release()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,18 +1,24 @@
 package handler
 
 import (
+	"CloudCamp/internal/config"
 	"CloudCamp/internal/domain/balancerDomain"
+	"CloudCamp/internal/limiter"
+	"CloudCamp/internal/metrics"
 	"CloudCamp/pkg/utils"
-	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
+	"sync/atomic"
+	"time"
 )
 
 // ProxyHandler обработчик для проксирования запросов
 type ProxyHandler struct {
-	balancer balancerDomain.Strategy
-	client   *http.Client
+	balancer  balancerDomain.RequestStrategy
+	transport http.RoundTripper
+	retry     atomic.Pointer[retryPolicy]
+	queue     atomic.Pointer[limiter.AdmissionQueue] // очередь запросов, ожидающих бэкенд (nil — без ожидания)
 }
 
 // ErrorResponse структура для ошибок, отправляемых пользователю
@@ -22,89 +28,138 @@ type ErrorResponse struct {
 }
 
 // NewProxyHandler создает новый обработчик прокси
-func NewProxyHandler(balancer balancerDomain.Strategy) *ProxyHandler {
-	return &ProxyHandler{
-		balancer: balancer,
-		client:   &http.Client{},
+func NewProxyHandler(balancer balancerDomain.Strategy, cfg config.ProxyConfig) *ProxyHandler {
+	h := &ProxyHandler{
+		balancer:  balancerDomain.AsRequestStrategy(balancer),
+		transport: http.DefaultTransport.(*http.Transport).Clone(),
 	}
+	h.UpdateConfig(cfg)
+
+	return h
+}
+
+// UpdateConfig применяет новые настройки проксирования к последующим запросам
+func (h *ProxyHandler) UpdateConfig(cfg config.ProxyConfig) {
+	h.retry.Store(newRetryPolicy(cfg.Retry))
+}
+
+// SetAdmissionQueue задает очередь, в которой запросы ждут бэкенд, если все доступные бэкенды
+// заняты или недоступны. Без очереди такие запросы сразу отклоняются
+func (h *ProxyHandler) SetAdmissionQueue(queue *limiter.AdmissionQueue) {
+	h.queue.Store(queue)
 }
 
 // ServeHTTP обрабатывает входящие HTTP-запросы
 func (h *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	const op = "handler.ProxyHandler.ServeHTTP"
 
-	// Получаем следующий доступный бэкенд
-	backend := h.balancer.NextBackend()
-	if backend == nil {
-		slog.Warn("No backend available")
-		utils.SendJSON(w,
-			http.StatusServiceUnavailable,
-			"No backend available",
-		)
+	// Запросы на смену протокола (WebSocket и т.п.) проксируются через туннель
+	if isUpgradeRequest(r) {
+		h.serveUpgrade(w, r)
 		return
 	}
 
-	// Инкрементируем количество подключений на выбранном бэкенде
-	backend.IncrementConnections()
-	defer backend.DecrementConnections() // Декрементируем количество подключений после завершения запроса
+	retry := h.retry.Load()
 
-	// Создаем URL для бэкенда
-	targetURL, err := url.Parse(backend.URL)
+	// Буферизуем тело запроса, чтобы его можно было отправить повторно на другой бэкенд
+	body, err := retry.bufferBody(r)
 	if err != nil {
 		slog.Error(op,
-			"invalid backend URL",
-			slog.String("backend", backend.URL),
+			"failed to read request body",
 			slog.String("error", err.Error()),
 		)
 		utils.SendJSON(w,
-			http.StatusInternalServerError,
-			"invalid backend URL",
+			http.StatusBadRequest,
+			"Failed to read request body",
 		)
 		return
 	}
 
-	// Создаем новый HTTP-запрос для проксирования
-	proxyReq, err := http.NewRequest(r.Method, targetURL.String()+r.RequestURI, r.Body)
-	if err != nil {
-		slog.Error(op,
-			"failed to create proxy request",
-			slog.String("backend", backend.URL),
-			slog.String("error", err.Error()),
-		)
-		utils.SendJSON(w,
-			http.StatusInternalServerError,
-			"Failed to create proxy request",
-		)
+	attempts := 1
+	if body.replayable {
+		attempts = retry.attemptsFor(r.Method)
+	}
+
+	rc := &balancerDomain.RoutingContext{
+		Request:  r,
+		ClientIP: utils.ClientIP(r),
+	}
+
+	var (
+		backend   *balancerDomain.Backend // бэкенд последней попытки, место на нем занято
+		busy      bool
+		proxyResp *http.Response
+	)
+
+	for attempt := 1; ; attempt++ {
+		// Получаем следующий доступный бэкенд, исключая уже опробованные.
+		// Выбранный бэкенд уже учитывает запрос в количестве подключений
+		next, nextBusy := h.nextBackend(r, rc)
+		if next == nil {
+			// Если повторять не на чем, клиент получает результат последней попытки
+			busy = nextBusy
+			break
+		}
+
+		// Новый бэкенд занят, результат предыдущей попытки больше не нужен
+		if backend != nil {
+			if proxyResp != nil {
+				_ = proxyResp.Body.Close()
+				proxyResp = nil
+			}
+			h.release(backend)
+		}
+		backend = next
+
+		start := time.Now()
+		proxyResp, err = h.send(r, backend, body)
+		metrics.ObserveProxy(backend.ID, r.Method, responseStatus(proxyResp, err), time.Since(start))
+		if err != nil {
+			slog.Error(op,
+				"failed to proxy request",
+				slog.String("backend", backend.URL),
+				slog.Int("attempt", attempt),
+				slog.String("error", err.Error()),
+			)
+		}
+		h.reportResult(r, backend, proxyResp, err, time.Since(start))
+
+		if attempt >= attempts || r.Context().Err() != nil || !retry.shouldRetry(proxyResp, err) {
+			break
+		}
+
+		// Попытка неудачна, пробуем следующий бэкенд
+		if proxyResp != nil {
+			slog.Warn("retrying request",
+				slog.String("backend", backend.URL),
+				slog.Int("attempt", attempt),
+				slog.Int("status", proxyResp.StatusCode),
+			)
+		}
+	}
+
+	if backend == nil {
+		h.sendNoBackend(w, r, busy)
 		return
 	}
-	proxyReq.Header = r.Header.Clone()
+	defer h.release(backend) // Декрементируем количество подключений после завершения запроса
 
-	// Отправляем запрос на бэкенд
-	proxyResp, err := h.client.Do(proxyReq)
 	if err != nil {
-		slog.Error(op,
-			"failed to proxy request",
-			slog.String("backend", backend.URL),
-			slog.String("error", err.Error()),
-		)
 		utils.SendJSON(w,
 			http.StatusBadGateway,
 			"Backend request failed",
 		)
-		h.balancer.MarkBackendDown(backend)
-
 		return
 	}
 	defer proxyResp.Body.Close()
 
-	// Копируем заголовки и тело ответа от бэкенда в клиентский ответ
-	for k, values := range proxyResp.Header {
-		for _, v := range values {
-			w.Header().Add(k, v)
-		}
+	// Передаем клиенту заголовки, тело и трейлеры ответа бэкенда
+	if err = writeResponse(w, proxyResp); err != nil {
+		slog.Warn("failed to copy response body",
+			slog.String("backend", backend.URL),
+			slog.String("error", err.Error()),
+		)
 	}
-	w.WriteHeader(proxyResp.StatusCode)
-	io.Copy(w, proxyResp.Body)
 
 	// Логируем успешный прокси запрос
 	slog.Info("proxying request",
@@ -113,3 +168,30 @@ func (h *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		slog.String("backend", backend.URL),
 	)
 }
+
+// send отправляет одну попытку запроса на указанный бэкенд
+func (h *ProxyHandler) send(r *http.Request, backend *balancerDomain.Backend, body *replayableBody) (*http.Response, error) {
+	// Создаем URL для бэкенда
+	targetURL, err := url.Parse(backend.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Создаем новый HTTP-запрос для проксирования
+	bodyReader, contentLength := body.reader()
+	proxyReq, err := newOutgoingRequest(r, targetURL, bodyReader, contentLength)
+	if err != nil {
+		return nil, err
+	}
+
+	// Отправляем запрос на бэкенд напрямую через транспорт, не следуя редиректам
+	return h.transport.RoundTrip(proxyReq)
+}
+
+// responseStatus возвращает статус ответа бэкенда или 0, если ответа нет
+func responseStatus(resp *http.Response, err error) int {
+	if err != nil || resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
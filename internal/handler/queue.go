@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"CloudCamp/internal/limiter"
+	"CloudCamp/pkg/utils"
+	"net/http"
+)
+
+// QueueHandler обработчик admin API для просмотра очереди запросов, ожидающих бэкенд
+type QueueHandler struct {
+	queue *limiter.AdmissionQueue
+}
+
+// QueueResponse состояние очереди в ответах admin API
+type QueueResponse struct {
+	MaxSize int                  `json:"max_size"` // 0 — без ограничения
+	Queued  int                  `json:"queued"`
+	Classes []QueueClassResponse `json:"classes"` // в порядке убывания приоритета
+}
+
+// QueueClassResponse состояние очереди класса приоритета
+type QueueClassResponse struct {
+	Class    string `json:"class"`
+	Timeout  string `json:"timeout"`
+	Queued   int    `json:"queued"`
+	Admitted uint64 `json:"admitted"`
+	Rejected uint64 `json:"rejected"`
+	TimedOut uint64 `json:"timed_out"`
+}
+
+// NewQueueHandler создает обработчик состояния очереди
+func NewQueueHandler(queue *limiter.AdmissionQueue) *QueueHandler {
+	return &QueueHandler{
+		queue: queue,
+	}
+}
+
+// ServeHTTP возвращает глубину очереди и счетчики классов: GET /admin/queue
+func (h *QueueHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.SendJSON(w,
+			http.StatusMethodNotAllowed,
+			"Method not allowed",
+		)
+		return
+	}
+
+	stats := h.queue.Stats()
+	resp := QueueResponse{
+		MaxSize: stats.MaxSize,
+		Queued:  stats.Queued,
+		Classes: make([]QueueClassResponse, 0, len(stats.Classes)),
+	}
+	for _, c := range stats.Classes {
+		resp.Classes = append(resp.Classes, QueueClassResponse{
+			Class:    c.Class,
+			Timeout:  c.Timeout.String(),
+			Queued:   c.Queued,
+			Admitted: c.Admitted,
+			Rejected: c.Rejected,
+			TimedOut: c.TimedOut,
+		})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
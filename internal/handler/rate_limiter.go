@@ -1,42 +1,57 @@
 package handler
 
 import (
-	"CloudCamp/internal/domain/limiter"
+	limiterDomain "CloudCamp/internal/domain/limiter"
+	"CloudCamp/internal/limiter"
+	"CloudCamp/internal/metrics"
 	"CloudCamp/pkg/utils"
 	"log/slog"
+	"math"
 	"net/http"
-	"strings"
+	"strconv"
+	"sync/atomic"
+	"time"
 )
 
 // RateLimiterMiddleware middleware для ограничения частоты запросов
 type RateLimiterMiddleware struct {
-	limiter limiter.RateLimiter
+	limiter  limiterDomain.RateLimiter
+	policies atomic.Pointer[limiter.PolicySet] // политики лимитов, заменяются при перезагрузке конфигурации
 }
 
 // NewRateLimiterMiddleware создает новый middleware для rate limiting
-func NewRateLimiterMiddleware(limiter limiter.RateLimiter) *RateLimiterMiddleware {
+func NewRateLimiterMiddleware(limiter limiterDomain.RateLimiter) *RateLimiterMiddleware {
 	return &RateLimiterMiddleware{
 		limiter: limiter,
 	}
 }
 
+// SetPolicies заменяет набор политик лимитов
+func (m *RateLimiterMiddleware) SetPolicies(policies *limiter.PolicySet) {
+	m.policies.Store(policies)
+}
+
 // Middleware возвращает HTTP middleware для rate limiting
 func (m *RateLimiterMiddleware) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Получаем IP-адрес клиента
-		clientIP := getClientIP(r)
+		// Получаем IP-адрес и идентификатор клиента из заголовка X-Client-ID или IP
+		clientIP := utils.ClientIP(r)
+		clientID := utils.ClientID(r)
 
-		// Получаем идентификатор клиента из заголовка или используем IP
-		clientID := r.Header.Get("X-Client-ID")
-		if clientID == "" {
-			clientID = clientIP
-		}
+		// Клиент, аутентифицированный по API-ключу или JWT, доступен следующим обработчикам
+		policies := m.policies.Load()
+		id := policies.Identify(r)
+		r = utils.WithAuthenticatedClient(r, id.ClientID())
 
-		// Проверяем, не превышен ли лимит
-		if !m.limiter.Allow(clientID) {
+		// Проверяем, не превышены ли лимит клиента и лимиты подходящих политик
+		decision := m.limiter.Allow(clientID, policies.Limits(r, id)...)
+		metrics.ObserveRateLimit(m.clientLabel(clientID), decision.Allowed)
+		setRateLimitHeaders(w.Header(), decision)
+		if !decision.Allowed {
 			slog.Warn("rate limit exceeded",
 				slog.String("client_id", clientID),
 				slog.String("client_ip", clientIP),
+				slog.String("limit", decision.Key),
 			)
 			utils.SendJSON(w,
 				http.StatusTooManyRequests,
@@ -50,28 +65,32 @@ func (m *RateLimiterMiddleware) Middleware(next http.Handler) http.Handler {
 	})
 }
 
-// getClientIP извлекает IP-адрес клиента из запроса
-func getClientIP(r *http.Request) string {
-	// Проверяем заголовок X-Forwarded-For
-	ip := r.Header.Get("X-Forwarded-For")
-	if ip != "" {
-		// Берем первый IP из списка
-		if i := strings.Index(ip, ","); i > 0 {
-			ip = ip[:i]
-		}
-		return strings.TrimSpace(ip)
+// setRateLimitHeaders добавляет заголовки RateLimit-* (draft-ietf-httpapi-ratelimit-headers)
+// и Retry-After для отклоненного запроса. Если лимиты к клиенту не применяются (например, запрос отклонен
+// из-за недоступного хранилища лимитов), заголовки RateLimit-* не добавляются, но Retry-After — всегда
+func setRateLimitHeaders(h http.Header, decision limiterDomain.Decision) {
+	if !decision.Allowed {
+		h.Set("Retry-After", strconv.Itoa(max(ceilSeconds(decision.RetryAfter), 1)))
 	}
-
-	// Проверяем заголовок X-Real-IP
-	ip = r.Header.Get("X-Real-IP")
-	if ip != "" {
-		return strings.TrimSpace(ip)
+	if decision.Limit <= 0 {
+		return
 	}
 
-	// Получаем IP из RemoteAddr
-	ip = r.RemoteAddr
-	if i := strings.LastIndex(ip, ":"); i > 0 {
-		ip = ip[:i]
+	h.Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+}
+
+// ceilSeconds округляет интервал вверх до целых секунд, чтобы клиент не повторил запрос раньше времени
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// clientLabel возвращает метку клиента для метрик: идентификатор клиента с индивидуальным лимитом,
+// иначе общее значение, чтобы произвольные ключи не порождали новые временные ряды
+func (m *RateLimiterMiddleware) clientLabel(clientID string) string {
+	if rate, _ := m.limiter.GetLimit(clientID); rate > 0 {
+		return clientID
 	}
-	return strings.TrimSpace(ip)
+	return metrics.DefaultClient
 }
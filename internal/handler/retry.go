@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"CloudCamp/internal/config"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+)
+
+// Виды ошибок транспорта, для которых можно настроить повтор
+const (
+	RetryErrorConnect = "connect" // не удалось установить соединение
+	RetryErrorTimeout = "timeout" // истек таймаут
+	RetryErrorReset   = "reset"   // соединение разорвано бэкендом
+)
+
+// defaultMaxBodySize максимальный размер тела запроса, буферизуемого для повтора по умолчанию
+const defaultMaxBodySize = 1 << 20
+
+// retryPolicy определяет, какие запросы и при каких ошибках повторяются на другом бэкенде
+type retryPolicy struct {
+	maxAttempts int
+	statusCodes map[int]bool
+	errors      map[string]bool
+	methods     map[string]bool
+	maxBodySize int64
+}
+
+// newRetryPolicy создает политику повторов из конфигурации, подставляя значения по умолчанию
+func newRetryPolicy(cfg config.RetryConfig) *retryPolicy {
+	p := &retryPolicy{
+		maxAttempts: cfg.MaxAttempts,
+		statusCodes: make(map[int]bool),
+		errors:      make(map[string]bool),
+		methods:     make(map[string]bool),
+		maxBodySize: cfg.MaxBodySize,
+	}
+
+	if p.maxAttempts < 1 {
+		p.maxAttempts = 1
+	}
+	if p.maxBodySize <= 0 {
+		p.maxBodySize = defaultMaxBodySize
+	}
+
+	for _, code := range cfg.StatusCodes {
+		p.statusCodes[code] = true
+	}
+
+	retryErrors := cfg.Errors
+	if len(retryErrors) == 0 {
+		retryErrors = []string{RetryErrorConnect, RetryErrorTimeout, RetryErrorReset}
+	}
+	for _, e := range retryErrors {
+		p.errors[e] = true
+	}
+
+	// По умолчанию повторяются только идемпотентные методы
+	methods := cfg.Methods
+	if len(methods) == 0 {
+		methods = []string{
+			http.MethodGet, http.MethodHead, http.MethodOptions,
+			http.MethodTrace, http.MethodPut, http.MethodDelete,
+		}
+	}
+	for _, m := range methods {
+		p.methods[strings.ToUpper(m)] = true
+	}
+
+	return p
+}
+
+// attemptsFor возвращает допустимое число попыток для метода запроса
+func (p *retryPolicy) attemptsFor(method string) int {
+	if !p.methods[method] {
+		return 1
+	}
+	return p.maxAttempts
+}
+
+// shouldRetry проверяет, нужно ли повторить запрос по результату попытки
+func (p *retryPolicy) shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return p.errors[classifyError(err)]
+	}
+	return p.statusCodes[resp.StatusCode]
+}
+
+// classifyError определяет вид ошибки транспорта
+func classifyError(err error) string {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return RetryErrorTimeout
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return RetryErrorConnect
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return RetryErrorReset
+	}
+
+	return ""
+}
+
+// replayableBody буферизованное тело запроса, которое можно отправить повторно
+type replayableBody struct {
+	data       []byte
+	rest       io.Reader // непрочитанный остаток тела, если оно больше лимита
+	length     int64     // исходная длина тела (-1, если неизвестна)
+	replayable bool
+}
+
+// bufferBody читает тело запроса в память не больше лимита.
+// Если тело больше лимита, оно будет отправлено один раз без возможности повтора
+func (p *retryPolicy) bufferBody(r *http.Request) (*replayableBody, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return &replayableBody{replayable: true}, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, p.maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > p.maxBodySize {
+		return &replayableBody{data: data, rest: r.Body, length: r.ContentLength}, nil
+	}
+	return &replayableBody{data: data, replayable: true}, nil
+}
+
+// reader возвращает тело для очередной попытки и его длину (-1, если длина неизвестна)
+func (b *replayableBody) reader() (io.Reader, int64) {
+	if b.rest != nil {
+		return io.MultiReader(bytes.NewReader(b.data), b.rest), b.length
+	}
+	if len(b.data) == 0 {
+		return nil, 0
+	}
+	return bytes.NewReader(b.data), int64(len(b.data))
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// hopHeaders заголовки, относящиеся к конкретному соединению, которые не передаются дальше (RFC 7230, 6.1)
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// removeHopHeaders удаляет hop-by-hop заголовки, включая перечисленные в Connection
+func removeHopHeaders(h http.Header) {
+	for _, value := range h.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				h.Del(name)
+			}
+		}
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+// newOutgoingRequest создает запрос к бэкенду по входящему запросу:
+// убирает hop-by-hop заголовки и добавляет X-Forwarded-*
+func newOutgoingRequest(r *http.Request, target *url.URL, body io.Reader, contentLength int64) (*http.Request, error) {
+	out, err := http.NewRequestWithContext(r.Context(), r.Method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	out.URL.Path, out.URL.RawPath = joinURLPath(target, r.URL)
+	out.URL.RawQuery = r.URL.RawQuery
+	out.ContentLength = contentLength
+	out.Header = r.Header.Clone()
+	if out.Header == nil {
+		out.Header = make(http.Header)
+	}
+	out.Trailer = r.Trailer
+
+	// Клиент, согласный принимать трейлеры, должен сообщить об этом и бэкенду
+	acceptsTrailers := strings.Contains(strings.ToLower(r.Header.Get("Te")), "trailers")
+	removeHopHeaders(out.Header)
+	if acceptsTrailers {
+		out.Header.Set("Te", "trailers")
+	}
+
+	setForwardedHeaders(out, r)
+
+	return out, nil
+}
+
+// setForwardedHeaders дописывает адрес клиента в X-Forwarded-For и выставляет X-Forwarded-Host/Proto
+func setForwardedHeaders(out, in *http.Request) {
+	if ip, _, err := net.SplitHostPort(in.RemoteAddr); err == nil {
+		if prior := in.Header.Values("X-Forwarded-For"); len(prior) > 0 {
+			ip = strings.Join(prior, ", ") + ", " + ip
+		}
+		out.Header.Set("X-Forwarded-For", ip)
+	}
+
+	out.Header.Set("X-Forwarded-Host", in.Host)
+	if in.TLS != nil {
+		out.Header.Set("X-Forwarded-Proto", "https")
+	} else {
+		out.Header.Set("X-Forwarded-Proto", "http")
+	}
+}
+
+// writeResponse передает клиенту ответ бэкенда: заголовки без hop-by-hop,
+// тело с немедленной отправкой потоковых ответов и трейлеры
+func writeResponse(w http.ResponseWriter, resp *http.Response) error {
+	removeHopHeaders(resp.Header)
+	for k, values := range resp.Header {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+
+	// Объявляем трейлеры заранее, чтобы передать их после тела
+	announced := len(resp.Trailer)
+	if announced > 0 {
+		names := make([]string, 0, announced)
+		for k := range resp.Trailer {
+			names = append(names, k)
+		}
+		w.Header().Set("Trailer", strings.Join(names, ", "))
+	}
+
+	w.WriteHeader(resp.StatusCode)
+
+	err := copyResponseBody(w, resp.Body, isStreamingResponse(resp))
+
+	// Трейлеры, появившиеся только после чтения тела, отправляются через http.TrailerPrefix
+	if len(resp.Trailer) == announced {
+		for k, values := range resp.Trailer {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+	} else {
+		for k, values := range resp.Trailer {
+			for _, v := range values {
+				w.Header().Add(http.TrailerPrefix+k, v)
+			}
+		}
+	}
+
+	return err
+}
+
+// isStreamingResponse проверяет, нужно ли отправлять тело клиенту сразу по мере получения
+// (SSE и ответы без известной длины, например chunked)
+func isStreamingResponse(resp *http.Response) bool {
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "text/event-stream" {
+		return true
+	}
+	return resp.ContentLength == -1
+}
+
+// copyResponseBody копирует тело ответа, при необходимости сбрасывая буфер после каждой записи
+func copyResponseBody(w http.ResponseWriter, body io.Reader, flush bool) error {
+	rc := http.NewResponseController(w)
+	buf := make([]byte, 32*1024)
+
+	for {
+		n, readErr := body.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+			if flush {
+				_ = rc.Flush()
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// joinURLPath склеивает базовый путь бэкенда и путь запроса через один слеш, как httputil.ReverseProxy.
+// Если в одном из путей есть экранированные символы (например, %2F), склеиваются и экранированные формы,
+// чтобы бэкенд получил путь в том виде, в каком его прислал клиент
+func joinURLPath(base, in *url.URL) (path, rawPath string) {
+	if base.RawPath == "" && in.RawPath == "" {
+		return joinPath(base.Path, in.Path), ""
+	}
+
+	// Слеш на стыке определяется по экранированным путям, чтобы RawPath оставался кодировкой Path
+	escapedBase, escapedIn := base.EscapedPath(), in.EscapedPath()
+	baseSlash, inSlash := strings.HasSuffix(escapedBase, "/"), strings.HasPrefix(escapedIn, "/")
+	switch {
+	case baseSlash && inSlash:
+		return base.Path + in.Path[1:], escapedBase + escapedIn[1:]
+	case !baseSlash && !inSlash:
+		return base.Path + "/" + in.Path, escapedBase + "/" + escapedIn
+	default:
+		return base.Path + in.Path, escapedBase + escapedIn
+	}
+}
+
+// joinPath склеивает два пути через один слеш
+func joinPath(base, path string) string {
+	switch {
+	case base == "":
+		return path
+	case strings.HasSuffix(base, "/") && strings.HasPrefix(path, "/"):
+		return base + path[1:]
+	case !strings.HasSuffix(base, "/") && !strings.HasPrefix(path, "/"):
+		return base + "/" + path
+	default:
+		return base + path
+	}
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"CloudCamp/internal/domain/balancerDomain"
+	"CloudCamp/internal/metrics"
+	"CloudCamp/pkg/utils"
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Таймауты установки туннеля до бэкенда
+const (
+	upgradeDialTimeout      = 10 * time.Second
+	upgradeHandshakeTimeout = 30 * time.Second
+)
+
+// isUpgradeRequest проверяет, запрашивает ли клиент смену протокола (например, WebSocket)
+func isUpgradeRequest(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// serveUpgrade проксирует запрос на смену протокола: устанавливает соединение с бэкендом,
+// передает handshake и после ответа 101 перекачивает данные в обе стороны.
+// Соединение учитывается в ActiveConnections бэкенда на все время жизни туннеля
+func (h *ProxyHandler) serveUpgrade(w http.ResponseWriter, r *http.Request) {
+	const op = "handler.ProxyHandler.serveUpgrade"
+
+	rc := &balancerDomain.RoutingContext{
+		Request:  r,
+		ClientIP: utils.ClientIP(r),
+	}
+
+	backend, busy := h.nextBackend(r, rc)
+	if backend == nil {
+		h.sendNoBackend(w, r, busy)
+		return
+	}
+	defer h.release(backend)
+
+	start := time.Now()
+	backendConn, resp, err := h.dialUpgrade(r, backend)
+	metrics.ObserveProxy(backend.ID, r.Method, responseStatus(resp, err), time.Since(start))
+	h.reportResult(r, backend, resp, err, time.Since(start))
+	if err != nil {
+		slog.Error(op,
+			"failed to upgrade connection",
+			slog.String("backend", backend.URL),
+			slog.String("error", err.Error()),
+		)
+		utils.SendJSON(w,
+			http.StatusBadGateway,
+			"Backend request failed",
+		)
+		return
+	}
+	defer backendConn.Close()
+
+	// Бэкенд отказался менять протокол — возвращаем его ответ как обычный
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+		_ = writeResponse(w, resp)
+		return
+	}
+
+	clientConn, clientBuf, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		slog.Error(op,
+			"failed to hijack client connection",
+			slog.String("error", err.Error()),
+		)
+		utils.SendJSON(w,
+			http.StatusInternalServerError,
+			"Connection upgrade is not supported",
+		)
+		return
+	}
+	defer clientConn.Close()
+
+	// Передаем клиенту ответ 101 с заголовками бэкенда
+	if _, err = fmt.Fprintf(clientBuf, "HTTP/1.1 %s\r\n", resp.Status); err == nil {
+		if err = resp.Header.Write(clientBuf); err == nil {
+			if _, err = clientBuf.WriteString("\r\n"); err == nil {
+				err = clientBuf.Flush()
+			}
+		}
+	}
+	if err != nil {
+		slog.Error(op,
+			"failed to write upgrade response",
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+
+	slog.Info("connection upgraded",
+		slog.String("protocol", r.Header.Get("Upgrade")),
+		slog.String("path", r.URL.Path),
+		slog.String("backend", backend.URL),
+	)
+
+	// Перекачиваем данные в обе стороны до закрытия любой из них
+	errc := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(backendConn, clientBuf)
+		errc <- err
+	}()
+	go func() {
+		_, err := io.Copy(clientConn, resp.Body)
+		errc <- err
+	}()
+	<-errc
+}
+
+// dialUpgrade открывает соединение с бэкендом, отправляет handshake и читает ответ.
+// При ответе 101 тело ответа позволяет читать данные бэкенда, уже попавшие в буфер
+func (h *ProxyHandler) dialUpgrade(r *http.Request, backend *balancerDomain.Backend) (net.Conn, *http.Response, error) {
+	targetURL, err := url.Parse(backend.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out, err := newOutgoingRequest(r, targetURL, nil, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Возвращаем заголовки смены протокола, удаленные как hop-by-hop
+	out.Header.Set("Connection", "Upgrade")
+	out.Header.Set("Upgrade", r.Header.Get("Upgrade"))
+
+	conn, err := dialBackend(targetURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_ = conn.SetDeadline(time.Now().Add(upgradeHandshakeTimeout))
+	if err = out.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, out)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		resp.Body = io.NopCloser(br)
+	}
+
+	return conn, resp, nil
+}
+
+// dialBackend устанавливает TCP (или TLS для https/wss) соединение с бэкендом
+func dialBackend(target *url.URL) (net.Conn, error) {
+	host := target.Host
+	secure := target.Scheme == "https" || target.Scheme == "wss"
+	if target.Port() == "" {
+		if secure {
+			host = net.JoinHostPort(target.Hostname(), "443")
+		} else {
+			host = net.JoinHostPort(target.Hostname(), "80")
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: upgradeDialTimeout}
+	if secure {
+		return tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: target.Hostname()})
+	}
+	return dialer.Dial("tcp", host)
+}
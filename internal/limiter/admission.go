@@ -0,0 +1,312 @@
+package limiter
+
+import (
+	"CloudCamp/internal/config"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// admissionRetryInterval как часто ожидающие запросы повторяют попытку, даже если место не освобождалось
+// (например, бэкенд вернулся в ротацию после проверки здоровья)
+const admissionRetryInterval = 50 * time.Millisecond
+
+// Причины, по которым запрос не допущен из очереди
+var (
+	ErrQueueFull    = errors.New("admission queue is full")
+	ErrQueueTimeout = errors.New("admission queue timeout")
+)
+
+// AdmissionQueue очередь запросов, для которых нет свободного бэкенда. Запросы допускаются
+// в порядке приоритета классов, внутри класса — в порядке поступления. При переполнении
+// запрос более приоритетного класса вытесняет последний запрос наименее приоритетного
+type AdmissionQueue struct {
+	mu           sync.Mutex
+	enabled      bool
+	maxSize      int
+	header       string
+	timeout      time.Duration // время ожидания для классов без собственного
+	classes      []*admissionClass
+	byName       map[string]*admissionClass
+	byClient     map[string]*admissionClass
+	size         atomic.Int64 // число ожидающих запросов, изменяется под мьютексом
+	lastDispatch time.Time
+}
+
+// admissionClass класс приоритета и его очередь
+type admissionClass struct {
+	name     string
+	timeout  time.Duration
+	waiters  []*admissionWaiter
+	admitted uint64
+	rejected uint64
+	timedOut uint64
+}
+
+// admissionWaiter запрос, ожидающий в очереди
+type admissionWaiter struct {
+	class *admissionClass
+	try   func() bool // попытка занять бэкенд, вызывается под мьютексом очереди
+	done  chan struct{}
+	err   error
+}
+
+// AdmissionStats состояние очереди
+type AdmissionStats struct {
+	MaxSize int
+	Queued  int
+	Classes []AdmissionClassStats // в порядке убывания приоритета
+}
+
+// AdmissionClassStats состояние очереди класса. Счетчики накапливаются с момента запуска
+type AdmissionClassStats struct {
+	Class    string
+	Timeout  time.Duration
+	Queued   int
+	Admitted uint64 // допущены после ожидания
+	Rejected uint64 // отклонены или вытеснены из переполненной очереди
+	TimedOut uint64 // не дождались бэкенда или отменены клиентом
+}
+
+// NewAdmissionQueue создает очередь по конфигурации
+func NewAdmissionQueue(cfg config.ConcurrencyConfig) *AdmissionQueue {
+	q := &AdmissionQueue{}
+	q.UpdateConfig(cfg)
+	return q
+}
+
+// UpdateConfig применяет новые настройки. Счетчики и ожидающие запросы сохраняются,
+// запросы удаленных классов переходят в класс default
+func (q *AdmissionQueue) UpdateConfig(cfg config.ConcurrencyConfig) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.enabled = cfg.Enabled
+	q.maxSize = cfg.Queue.MaxSize
+	q.header = cfg.Queue.PriorityHeader
+	q.timeout = cfg.QueueTimeout
+
+	classes := cfg.Queue.Classes
+	if !hasClass(classes, config.DefaultPriorityClass) {
+		classes = append(classes[:len(classes):len(classes)], config.PriorityClass{Name: config.DefaultPriorityClass})
+	}
+
+	old := q.byName
+	q.classes = make([]*admissionClass, 0, len(classes))
+	q.byName = make(map[string]*admissionClass, len(classes))
+	q.byClient = make(map[string]*admissionClass)
+	for _, pc := range classes {
+		c, ok := old[pc.Name]
+		if !ok {
+			c = &admissionClass{name: pc.Name}
+		}
+		c.timeout = pc.Timeout
+		q.classes = append(q.classes, c)
+		q.byName[c.name] = c
+
+		for _, clientID := range pc.Clients {
+			if _, exists := q.byClient[clientID]; !exists {
+				q.byClient[clientID] = c
+			}
+		}
+	}
+
+	def := q.byName[config.DefaultPriorityClass]
+	for name, c := range old {
+		if _, ok := q.byName[name]; ok {
+			continue
+		}
+		for _, w := range c.waiters {
+			w.class = def
+		}
+		def.waiters = append(def.waiters, c.waiters...)
+	}
+
+	q.dispatch()
+}
+
+// Class возвращает класс приоритета запроса: из заголовка приоритета, если в нем указан известный класс,
+// иначе по ID клиента, иначе default
+func (q *AdmissionQueue) Class(clientID string, header http.Header) string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.header != "" {
+		if c, ok := q.byName[header.Get(q.header)]; ok {
+			return c.name
+		}
+	}
+	if c, ok := q.byClient[clientID]; ok {
+		return c.name
+	}
+	return config.DefaultPriorityClass
+}
+
+// Wait допускает запрос: вызывает try и, если бэкенд не найден, ставит запрос в очередь класса
+// и повторяет попытки при освобождении места, не дольше таймаута класса или до отмены контекста.
+// Если очередь пуста, запрос не ждет и не проходит через мьютекс очереди
+func (q *AdmissionQueue) Wait(ctx context.Context, class string, try func() bool) error {
+	if q.size.Load() == 0 && try() {
+		return nil
+	}
+
+	q.mu.Lock()
+	c := q.byName[class]
+	if c == nil {
+		c = q.byName[config.DefaultPriorityClass]
+	}
+	timeout := c.timeout
+	if timeout <= 0 {
+		timeout = q.timeout
+	}
+	if !q.enabled || timeout <= 0 {
+		q.mu.Unlock()
+		return ErrQueueTimeout
+	}
+	if q.maxSize > 0 && int(q.size.Load()) >= q.maxSize && !q.evictFor(c) {
+		c.rejected++
+		q.mu.Unlock()
+		return ErrQueueFull
+	}
+
+	w := &admissionWaiter{class: c, try: try, done: make(chan struct{})}
+	c.waiters = append(c.waiters, w)
+	q.size.Add(1)
+	q.dispatch()
+	q.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(admissionRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return w.err
+		case <-ticker.C:
+			q.retry()
+		case <-timer.C:
+			return q.cancel(w, ErrQueueTimeout)
+		case <-ctx.Done():
+			return q.cancel(w, ctx.Err())
+		}
+	}
+}
+
+// Notify сообщает об освобождении места на бэкенде: ожидающие запросы пробуют занять его
+func (q *AdmissionQueue) Notify() {
+	if q.size.Load() == 0 {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.dispatch()
+}
+
+// Stats возвращает глубину очереди и счетчики классов в порядке убывания приоритета
+func (q *AdmissionQueue) Stats() AdmissionStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := AdmissionStats{
+		MaxSize: q.maxSize,
+		Queued:  int(q.size.Load()),
+		Classes: make([]AdmissionClassStats, 0, len(q.classes)),
+	}
+	for _, c := range q.classes {
+		timeout := c.timeout
+		if timeout <= 0 {
+			timeout = q.timeout
+		}
+		stats.Classes = append(stats.Classes, AdmissionClassStats{
+			Class:    c.name,
+			Timeout:  timeout,
+			Queued:   len(c.waiters),
+			Admitted: c.admitted,
+			Rejected: c.rejected,
+			TimedOut: c.timedOut,
+		})
+	}
+	return stats
+}
+
+// retry повторяет попытки ожидающих запросов не чаще admissionRetryInterval
+func (q *AdmissionQueue) retry() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if time.Since(q.lastDispatch) >= admissionRetryInterval {
+		q.dispatch()
+	}
+}
+
+// dispatch пробует допустить ожидающие запросы в порядке приоритета. Запрос, для которого бэкенд
+// не нашелся, остается в очереди, но не мешает следующим: их бэкенды могут быть свободны
+// (например, при consistent hashing). Вызывается под мьютексом
+func (q *AdmissionQueue) dispatch() {
+	q.lastDispatch = time.Now()
+	for _, c := range q.classes {
+		waiting := c.waiters[:0]
+		for _, w := range c.waiters {
+			if !w.try() {
+				waiting = append(waiting, w)
+				continue
+			}
+			c.admitted++
+			q.size.Add(-1)
+			close(w.done)
+		}
+		clear(c.waiters[len(waiting):])
+		c.waiters = waiting
+	}
+}
+
+// evictFor вытесняет последний запрос наименее приоритетного класса, если он ниже приоритетом, чем c.
+// Вызывается под мьютексом
+func (q *AdmissionQueue) evictFor(c *admissionClass) bool {
+	for i := len(q.classes) - 1; i >= 0 && q.classes[i] != c; i-- {
+		lower := q.classes[i]
+		if n := len(lower.waiters); n > 0 {
+			w := lower.waiters[n-1]
+			lower.waiters[n-1] = nil
+			lower.waiters = lower.waiters[:n-1]
+			lower.rejected++
+			q.size.Add(-1)
+			w.err = ErrQueueFull
+			close(w.done)
+			return true
+		}
+	}
+	return false
+}
+
+// cancel убирает запрос из очереди. Если запрос уже допущен или вытеснен, возвращает этот результат
+func (q *AdmissionQueue) cancel(w *admissionWaiter, err error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	c := w.class
+	for i, waiter := range c.waiters {
+		if waiter == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			c.timedOut++
+			q.size.Add(-1)
+			return err
+		}
+	}
+	return w.err
+}
+
+// hasClass проверяет, есть ли класс с указанным именем
+func hasClass(classes []config.PriorityClass, name string) bool {
+	for _, c := range classes {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
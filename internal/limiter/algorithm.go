@@ -0,0 +1,239 @@
+package limiter
+
+import (
+	"CloudCamp/internal/config"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// limitAlgorithm состояние лимита одного ключа. Реализации считают в непрерывном времени,
+// поэтому доступные запросы дробные и частые проверки не теряют накопленную часть токена
+type limitAlgorithm interface {
+	available(now time.Time) float64                  // запросы, доступные к моменту now; не меняет состояние
+	take(now time.Time)                               // учитывает пропущенный запрос
+	compact(now time.Time)                            // освобождает устаревшее состояние, не меняя результат available
+	wait(now time.Time, tokens float64) time.Duration // через сколько после now станет доступно tokens запросов
+}
+
+// newLimitAlgorithm создает состояние алгоритма с лимитом rate запросов за per,
+// в котором к моменту now доступно tokens запросов
+func newLimitAlgorithm(name string, rate int, per time.Duration, tokens float64, now time.Time) limitAlgorithm {
+	tokens = math.Max(0, math.Min(tokens, float64(rate)))
+
+	switch name {
+	case config.AlgorithmSlidingWindowLog:
+		w := &slidingWindowLog{rate: rate, per: per}
+		for i := 0; i < rate-int(math.Floor(tokens)); i++ {
+			w.log = append(w.log, now)
+		}
+		return w
+	case config.AlgorithmSlidingWindowCounter:
+		return &slidingWindowCounter{rate: float64(rate), per: per, start: now, curr: float64(rate) - tokens}
+	case config.AlgorithmGCRA:
+		g := &gcra{rate: float64(rate), per: per}
+		g.tat = now.Add(time.Duration((float64(rate) - tokens) * float64(g.interval())))
+		return g
+	default:
+		return &TokenBucket{rate: float64(rate), per: per, tokens: tokens, last: now}
+	}
+}
+
+// checkAlgorithm проверяет, что алгоритм с таким именем существует
+func checkAlgorithm(name string) error {
+	switch name {
+	case config.AlgorithmTokenBucket, config.AlgorithmSlidingWindowLog, config.AlgorithmSlidingWindowCounter, config.AlgorithmGCRA:
+		return nil
+	default:
+		return fmt.Errorf("unknown rate limit algorithm %q", name)
+	}
+}
+
+// TokenBucket реализует алгоритм Token Bucket: токены пополняются равномерно, rate токенов за per
+type TokenBucket struct {
+	rate   float64       // емкость бакета
+	per    time.Duration // интервал полного пополнения
+	tokens float64       // токены на момент last
+	last   time.Time     // время последнего пересчета токенов
+}
+
+// available возвращает токены с учетом пополнения к моменту now
+func (b *TokenBucket) available(now time.Time) float64 {
+	if b.per <= 0 || !now.After(b.last) {
+		return b.tokens
+	}
+	return math.Min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate/b.per.Seconds())
+}
+
+// take списывает токен
+func (b *TokenBucket) take(now time.Time) {
+	b.tokens = b.available(now) - 1
+	b.last = now
+}
+
+// compact фиксирует пополнение к моменту now
+func (b *TokenBucket) compact(now time.Time) {
+	b.tokens = b.available(now)
+	b.last = now
+}
+
+// wait возвращает время пополнения до tokens токенов
+func (b *TokenBucket) wait(now time.Time, tokens float64) time.Duration {
+	return linearWait(b.available(now), tokens, b.rate, b.per)
+}
+
+// slidingWindowLog реализует скользящее окно с журналом: хранит время каждого пропущенного запроса
+// за последний период. Точен, но расходует память пропорционально rate
+type slidingWindowLog struct {
+	rate int
+	per  time.Duration
+	log  []time.Time // время пропущенных запросов по возрастанию
+}
+
+// expired возвращает число записей журнала, вышедших из окна к моменту now
+func (w *slidingWindowLog) expired(now time.Time) int {
+	if w.per <= 0 {
+		return 0
+	}
+	start := now.Add(-w.per)
+	return sort.Search(len(w.log), func(i int) bool { return w.log[i].After(start) })
+}
+
+// available возвращает число запросов, которые еще помещаются в окно
+func (w *slidingWindowLog) available(now time.Time) float64 {
+	return float64(w.rate - (len(w.log) - w.expired(now)))
+}
+
+// take записывает запрос в журнал
+func (w *slidingWindowLog) take(now time.Time) {
+	w.compact(now)
+	w.log = append(w.log, now)
+}
+
+// compact удаляет записи, вышедшие из окна
+func (w *slidingWindowLog) compact(now time.Time) {
+	if n := w.expired(now); n > 0 {
+		w.log = append(w.log[:0], w.log[n:]...)
+	}
+}
+
+// wait возвращает время, через которое из окна выйдет достаточно записей
+func (w *slidingWindowLog) wait(now time.Time, tokens float64) time.Duration {
+	expired := w.expired(now)
+	excess := len(w.log) - expired - (w.rate - min(int(math.Ceil(tokens)), w.rate))
+	if excess <= 0 || w.per <= 0 {
+		return 0
+	}
+	return max(w.log[expired+excess-1].Add(w.per).Sub(now), 0)
+}
+
+// slidingWindowCounter реализует скользящее окно со счетчиками: число запросов за последний период
+// оценивается как счетчик текущего окна плюс доля предыдущего, еще попадающая в скользящее окно
+type slidingWindowCounter struct {
+	rate  float64
+	per   time.Duration
+	start time.Time // начало текущего окна
+	prev  float64   // запросов в предыдущем окне
+	curr  float64   // запросов в текущем окне
+}
+
+// roll возвращает начало окна и счетчики, сдвинутые к окну, в которое попадает now
+func (w *slidingWindowCounter) roll(now time.Time) (time.Time, float64, float64) {
+	if w.per <= 0 || now.Sub(w.start) < w.per {
+		return w.start, w.prev, w.curr
+	}
+
+	windows := now.Sub(w.start) / w.per
+	start := w.start.Add(windows * w.per)
+	if windows == 1 {
+		return start, w.curr, 0
+	}
+	return start, 0, 0
+}
+
+// available возвращает оценку числа запросов, которые еще помещаются в окно
+func (w *slidingWindowCounter) available(now time.Time) float64 {
+	start, prev, curr := w.roll(now)
+	if w.per > 0 {
+		prev *= 1 - now.Sub(start).Seconds()/w.per.Seconds()
+	}
+	return w.rate - prev - curr
+}
+
+// take учитывает запрос в текущем окне
+func (w *slidingWindowCounter) take(now time.Time) {
+	w.compact(now)
+	w.curr++
+}
+
+// compact сдвигает окно к моменту now
+func (w *slidingWindowCounter) compact(now time.Time) {
+	w.start, w.prev, w.curr = w.roll(now)
+}
+
+// wait возвращает время, через которое оценка освободит tokens запросов. До конца текущего окна
+// доступное растет по мере убывания вклада предыдущего окна, затем — по мере убывания вклада текущего
+func (w *slidingWindowCounter) wait(now time.Time, tokens float64) time.Duration {
+	available := w.available(now)
+	if available >= tokens || w.per <= 0 {
+		return 0
+	}
+
+	start, prev, curr := w.roll(now)
+	if w.rate-curr >= tokens {
+		return time.Duration((tokens - available) / prev * float64(w.per))
+	}
+	windowLeft := start.Add(w.per).Sub(now)
+	return windowLeft + time.Duration((tokens-w.rate+curr)/curr*float64(w.per))
+}
+
+// gcra реализует Generic Cell Rate Algorithm. Хранится только теоретическое время прибытия (TAT):
+// каждый запрос сдвигает его на интервал per/rate, запрос допускается, пока TAT опережает текущее
+// время не больше чем на период. Это эквивалентно token bucket с емкостью rate, но требует одного значения
+type gcra struct {
+	rate float64
+	per  time.Duration
+	tat  time.Time
+}
+
+// interval возвращает интервал между запросами при равномерной нагрузке
+func (g *gcra) interval() time.Duration {
+	if g.rate <= 0 {
+		return g.per
+	}
+	return time.Duration(float64(g.per) / g.rate)
+}
+
+// available возвращает число запросов, которые допускаются к моменту now
+func (g *gcra) available(now time.Time) float64 {
+	if g.per <= 0 {
+		return g.rate
+	}
+	backlog := max(g.tat.Sub(now), 0)
+	return g.rate * (1 - backlog.Seconds()/g.per.Seconds())
+}
+
+// take сдвигает теоретическое время прибытия
+func (g *gcra) take(now time.Time) {
+	if now.After(g.tat) {
+		g.tat = now
+	}
+	g.tat = g.tat.Add(g.interval())
+}
+
+// compact не требуется: состояние GCRA не растет
+func (g *gcra) compact(time.Time) {}
+
+// wait возвращает время, через которое станет доступно tokens запросов
+func (g *gcra) wait(now time.Time, tokens float64) time.Duration {
+	return linearWait(g.available(now), tokens, g.rate, g.per)
+}
+
+// linearWait возвращает время до накопления tokens запросов при равномерном пополнении rate за per
+func linearWait(available, tokens, rate float64, per time.Duration) time.Duration {
+	if available >= tokens || rate <= 0 || per <= 0 {
+		return 0
+	}
+	return time.Duration((tokens - available) / rate * float64(per))
+}
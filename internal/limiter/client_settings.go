@@ -23,6 +23,15 @@ func NewClientSettings(defaultRate int, defaultPer time.Duration) *ClientSetting
 	}
 }
 
+// SetDefaults обновляет настройки по умолчанию, не затрагивая индивидуальные настройки клиентов
+func (cs *ClientSettings) SetDefaults(defaultRate int, defaultPer time.Duration) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.defRate = defaultRate
+	cs.defPer = defaultPer
+}
+
 // GetSettings возвращает настройки для клиента или дефолтные значения
 func (cs *ClientSettings) GetSettings(clientID string) (int, time.Duration) {
 	cs.mu.RLock()
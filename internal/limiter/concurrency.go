@@ -0,0 +1,170 @@
+package limiter
+
+import (
+	"CloudCamp/internal/config"
+	"context"
+	"sync"
+	"time"
+)
+
+// ConcurrencyLimiter ограничивает число одновременных запросов для каждого ключа клиента.
+// Запросы сверх лимита ждут в очереди (FIFO) освобождения места не дольше queue_timeout
+type ConcurrencyLimiter struct {
+	mu           sync.Mutex
+	enabled      bool
+	limit        int            // лимит по умолчанию (0 — без ограничения)
+	limits       map[string]int // индивидуальные лимиты клиентов
+	queueTimeout time.Duration
+	inflight     map[string]int
+	waiters      map[string][]chan struct{} // очереди ожидающих запросов; закрытие канала передает место
+}
+
+// NewConcurrencyLimiter создает лимитер одновременных запросов по конфигурации
+func NewConcurrencyLimiter(cfg config.ConcurrencyConfig) *ConcurrencyLimiter {
+	c := &ConcurrencyLimiter{
+		inflight: make(map[string]int),
+		waiters:  make(map[string][]chan struct{}),
+	}
+	c.UpdateConfig(cfg)
+	return c
+}
+
+// UpdateConfig применяет новые лимиты. Выполняющиеся запросы не прерываются,
+// а ожидающие получают место, если новый лимит это позволяет
+func (c *ConcurrencyLimiter) UpdateConfig(cfg config.ConcurrencyConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.enabled = cfg.Enabled
+	c.limit = cfg.PerClient
+	c.limits = make(map[string]int, len(cfg.Clients))
+	for clientID, limit := range cfg.Clients {
+		c.limits[clientID] = limit
+	}
+	c.queueTimeout = cfg.QueueTimeout
+
+	for key := range c.waiters {
+		c.dispatch(key)
+	}
+}
+
+// Acquire занимает место для запроса клиента, при необходимости ожидая его не дольше queue_timeout
+// или до отмены контекста. Возвращает функцию освобождения места и false, если место не получено
+func (c *ConcurrencyLimiter) Acquire(ctx context.Context, key string) (func(), bool) {
+	c.mu.Lock()
+	if !c.enabled {
+		c.mu.Unlock()
+		return func() {}, true
+	}
+
+	limit := c.limitFor(key)
+	if limit <= 0 || (c.inflight[key] < limit && len(c.waiters[key]) == 0) {
+		c.inflight[key]++
+		c.mu.Unlock()
+		return c.releaseFunc(key), true
+	}
+
+	timeout := c.queueTimeout
+	if timeout <= 0 {
+		c.mu.Unlock()
+		return nil, false
+	}
+
+	granted := make(chan struct{})
+	c.waiters[key] = append(c.waiters[key], granted)
+	c.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-granted:
+		return c.releaseFunc(key), true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.removeWaiter(key, granted) {
+		// Место передано одновременно с истечением ожидания: возвращаем его следующему в очереди
+		c.release(key)
+	}
+	return nil, false
+}
+
+// InFlight возвращает число выполняющихся запросов клиента
+func (c *ConcurrencyLimiter) InFlight(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.inflight[key]
+}
+
+// Queued возвращает число запросов клиента, ожидающих места
+func (c *ConcurrencyLimiter) Queued(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters[key])
+}
+
+// limitFor возвращает лимит клиента. Вызывается под мьютексом
+func (c *ConcurrencyLimiter) limitFor(key string) int {
+	if limit, ok := c.limits[key]; ok {
+		return limit
+	}
+	return c.limit
+}
+
+// releaseFunc возвращает функцию, освобождающую место клиента один раз
+func (c *ConcurrencyLimiter) releaseFunc(key string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.release(key)
+		})
+	}
+}
+
+// release освобождает место и передает его ожидающим запросам. Вызывается под мьютексом
+func (c *ConcurrencyLimiter) release(key string) {
+	c.inflight[key]--
+	c.dispatch(key)
+	if c.inflight[key] <= 0 && len(c.waiters[key]) == 0 {
+		delete(c.inflight, key)
+		delete(c.waiters, key)
+	}
+}
+
+// dispatch передает свободные места ожидающим запросам в порядке очереди. Вызывается под мьютексом
+func (c *ConcurrencyLimiter) dispatch(key string) {
+	queue := c.waiters[key]
+	limit := c.limitFor(key)
+	for len(queue) > 0 && (!c.enabled || limit <= 0 || c.inflight[key] < limit) {
+		c.inflight[key]++
+		close(queue[0])
+		queue = queue[1:]
+	}
+
+	if len(queue) == 0 {
+		delete(c.waiters, key)
+	} else {
+		c.waiters[key] = queue
+	}
+}
+
+// removeWaiter убирает запрос из очереди. Возвращает false, если место ему уже передано
+func (c *ConcurrencyLimiter) removeWaiter(key string, granted chan struct{}) bool {
+	queue := c.waiters[key]
+	for i, ch := range queue {
+		if ch == granted {
+			c.waiters[key] = append(queue[:i:i], queue[i+1:]...)
+			if len(c.waiters[key]) == 0 {
+				delete(c.waiters, key)
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,298 @@
+package limiter
+
+import (
+	domain "CloudCamp/internal/domain/limiter"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Операции журнала клиентов
+const (
+	opSet    = "set"
+	opDelete = "delete"
+	opBatch  = "batch" // несколько изменений одной строкой, чтобы оборванная запись не применила их частично
+)
+
+// minCompactOps минимальное число записей в журнале, после которого он может быть сжат
+const minCompactOps = 1000
+
+// logEntry строка журнала клиентов в формате JSON
+type logEntry struct {
+	Op       string     `json:"op"`
+	ClientID string     `json:"client_id"`
+	Rate     int        `json:"rate,omitempty"`
+	Period   string     `json:"period,omitempty"`
+	Entries  []logEntry `json:"entries,omitempty"` // изменения пакета (op=batch)
+}
+
+// FileClientStore хранит клиентов в файле-журнале: каждое изменение дописывается строкой JSON
+// и сбрасывается на диск. При открытии и по мере роста журнал сжимается до снимка актуальных записей
+type FileClientStore struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	records map[string]domain.ClientRecord
+	ops     int // число строк в текущем файле журнала
+}
+
+// OpenFileClientStore открывает журнал клиентов, создавая его при отсутствии
+func OpenFileClientStore(path string) (*FileClientStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create client store dir: %w", err)
+	}
+
+	s := &FileClientStore{
+		path:    path,
+		records: make(map[string]domain.ClientRecord),
+	}
+	if err := s.replay(); err != nil {
+		return nil, err
+	}
+
+	// Сжимаем журнал при открытии, чтобы он не рос от перезапуска к перезапуску
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Load возвращает все сохраненные записи, упорядоченные по идентификатору клиента
+func (s *FileClientStore) Load() []domain.ClientRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]domain.ClientRecord, 0, len(s.records))
+	for _, r := range s.records {
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ClientID < records[j].ClientID })
+
+	return records
+}
+
+// Get возвращает запись клиента
+func (s *FileClientStore) Get(clientID string) (domain.ClientRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[clientID]
+	return r, ok
+}
+
+// Save сохраняет лимит клиента
+func (s *FileClientStore) Save(clientID string, rate int, period time.Duration) error {
+	return s.append(logEntry{Op: opSet, ClientID: clientID, Rate: rate, Period: period.String()})
+}
+
+// SaveAll сохраняет лимиты нескольких клиентов одной строкой журнала
+func (s *FileClientStore) SaveAll(records []domain.ClientRecord) error {
+	e := logEntry{Op: opBatch, Entries: make([]logEntry, 0, len(records))}
+	for _, r := range records {
+		e.Entries = append(e.Entries, logEntry{Op: opSet, ClientID: r.ClientID, Rate: r.Rate, Period: r.Period.String()})
+	}
+	return s.append(e)
+}
+
+// Delete сохраняет отметку об удалении клиента
+func (s *FileClientStore) Delete(clientID string) error {
+	return s.append(logEntry{Op: opDelete, ClientID: clientID})
+}
+
+// Close закрывает файл журнала
+func (s *FileClientStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// append дописывает изменение в журнал, дожидается записи на диск и только затем применяет его в памяти
+func (s *FileClientStore) append(e logEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return errors.New("client store is closed")
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err = s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write client store: %w", err)
+	}
+	if err = s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync client store: %w", err)
+	}
+
+	s.ops++
+	if err = s.apply(e); err != nil {
+		return err
+	}
+
+	if s.ops >= minCompactOps && s.ops > 2*len(s.records) {
+		if err = s.compact(); err != nil {
+			slog.Warn("failed to compact client store", slog.String("path", s.path), slog.String("error", err.Error()))
+		}
+	}
+	return nil
+}
+
+// replay читает журнал и восстанавливает записи. Оборванная последняя строка
+// (например, после аварийной остановки во время записи) пропускается
+func (s *FileClientStore) replay() error {
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open client store: %w", err)
+	}
+	defer file.Close()
+
+	// Строки читаются без ограничения длины: пакет импорта записывается одной строкой
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		data, readErr := reader.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return fmt.Errorf("failed to read client store: %w", readErr)
+		}
+
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			var e logEntry
+			if err = json.Unmarshal(data, &e); err == nil {
+				err = s.apply(e)
+			}
+			if err != nil {
+				slog.Warn("skipping corrupted client store entry",
+					slog.String("path", s.path),
+					slog.Int("line", line),
+					slog.String("error", err.Error()),
+				)
+			}
+		}
+
+		if readErr != nil {
+			return nil
+		}
+	}
+}
+
+// apply применяет изменение журнала к записям в памяти. Пакет применяется, только если корректны все его изменения
+func (s *FileClientStore) apply(e logEntry) error {
+	if e.Op != opBatch {
+		r, err := entryRecord(e)
+		if err != nil {
+			return err
+		}
+		s.records[r.ClientID] = r
+		return nil
+	}
+
+	records := make([]domain.ClientRecord, 0, len(e.Entries))
+	for _, entry := range e.Entries {
+		r, err := entryRecord(entry)
+		if err != nil {
+			return err
+		}
+		records = append(records, r)
+	}
+	for _, r := range records {
+		s.records[r.ClientID] = r
+	}
+	return nil
+}
+
+// entryRecord преобразует изменение журнала (set или delete) в запись клиента
+func entryRecord(e logEntry) (domain.ClientRecord, error) {
+	if e.ClientID == "" {
+		return domain.ClientRecord{}, errors.New("empty client_id")
+	}
+
+	switch e.Op {
+	case opSet:
+		period, err := time.ParseDuration(e.Period)
+		if err != nil {
+			return domain.ClientRecord{}, fmt.Errorf("invalid period %q: %w", e.Period, err)
+		}
+		return domain.ClientRecord{ClientID: e.ClientID, Rate: e.Rate, Period: period}, nil
+	case opDelete:
+		return domain.ClientRecord{ClientID: e.ClientID, Deleted: true}, nil
+	default:
+		return domain.ClientRecord{}, fmt.Errorf("unknown op %q", e.Op)
+	}
+}
+
+// compact записывает снимок актуальных записей во временный файл и атомарно заменяет им журнал
+func (s *FileClientStore) compact() error {
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create client store snapshot: %w", err)
+	}
+
+	ids := make([]string, 0, len(s.records))
+	for id := range s.records {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, id := range ids {
+		r := s.records[id]
+		e := logEntry{Op: opDelete, ClientID: id}
+		if !r.Deleted {
+			e = logEntry{Op: opSet, ClientID: id, Rate: r.Rate, Period: r.Period.String()}
+		}
+		if err = enc.Encode(e); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, s.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write client store snapshot: %w", err)
+	}
+
+	// Продолжаем дописывать изменения уже в новый файл. Старый дескриптор указывает на замененный файл,
+	// поэтому закрываем его в любом случае: запись в него потеряла бы изменения
+	if s.file != nil {
+		_ = s.file.Close()
+	}
+	s.file, err = os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open client store: %w", err)
+	}
+	s.ops = len(ids)
+
+	return nil
+}
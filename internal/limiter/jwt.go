@@ -0,0 +1,182 @@
+package limiter
+
+import (
+	"CloudCamp/internal/config"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// jwtVerifier проверяет подпись и срок действия JWT и извлекает subject
+type jwtVerifier struct {
+	secret    []byte         // ключ HS256
+	publicKey *rsa.PublicKey // ключ RS256
+	issuer    string
+	audience  string
+}
+
+// jwtHeader заголовок JWT
+type jwtHeader struct {
+	Alg string `json:"alg"`
+}
+
+// jwtClaims поля JWT, используемые при проверке
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"` // строка или массив строк
+	ExpiresAt *float64        `json:"exp"`
+	NotBefore *float64        `json:"nbf"`
+}
+
+// newJWTVerifier создает проверку JWT по конфигурации. Если проверка не настроена, возвращает nil
+func newJWTVerifier(cfg config.JWTConfig) (*jwtVerifier, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	v := &jwtVerifier{
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+	}
+	if cfg.Secret != "" {
+		v.secret = []byte(cfg.Secret)
+	}
+	if cfg.PublicKeyFile != "" {
+		key, err := loadRSAPublicKey(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		v.publicKey = key
+	}
+	return v, nil
+}
+
+// loadRSAPublicKey читает открытый ключ RSA из PEM (PKIX или PKCS#1)
+func loadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwt public key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("jwt public key %s is not PEM encoded", path)
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse jwt public key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("jwt public key %s is not an RSA key", path)
+	}
+	return key, nil
+}
+
+// Subject проверяет токен и возвращает его subject
+func (v *jwtVerifier) Subject(token string, now time.Time) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return "", err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.New("malformed signature")
+	}
+	if err = v.verify(header.Alg, parts[0]+"."+parts[1], signature); err != nil {
+		return "", err
+	}
+
+	var claims jwtClaims
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return "", err
+	}
+	if claims.ExpiresAt != nil && !now.Before(time.Unix(int64(*claims.ExpiresAt), 0)) {
+		return "", errors.New("token is expired")
+	}
+	if claims.NotBefore != nil && now.Before(time.Unix(int64(*claims.NotBefore), 0)) {
+		return "", errors.New("token is not valid yet")
+	}
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return "", errors.New("unexpected issuer")
+	}
+	if v.audience != "" && !claims.hasAudience(v.audience) {
+		return "", errors.New("unexpected audience")
+	}
+	if claims.Subject == "" {
+		return "", errors.New("token has no subject")
+	}
+	return claims.Subject, nil
+}
+
+// verify проверяет подпись. Алгоритм из заголовка допускается, только если для него настроен ключ,
+// поэтому токен с alg=none или подписанный открытым ключом как секретом HS256 не пройдет проверку
+func (v *jwtVerifier) verify(alg, signed string, signature []byte) error {
+	switch {
+	case alg == "HS256" && v.secret != nil:
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case alg == "RS256" && v.publicKey != nil:
+		digest := sha256.Sum256([]byte(signed))
+		if err := rsa.VerifyPKCS1v15(v.publicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+}
+
+// hasAudience проверяет, что токен выдан для audience
+func (c jwtClaims) hasAudience(audience string) bool {
+	var single string
+	if json.Unmarshal(c.Audience, &single) == nil {
+		return single == audience
+	}
+
+	var list []string
+	if json.Unmarshal(c.Audience, &list) == nil {
+		for _, aud := range list {
+			if aud == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeSegment декодирует base64url-сегмент токена в JSON
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.New("malformed token segment")
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return errors.New("malformed token segment")
+	}
+	return nil
+}
@@ -1,85 +1,100 @@
 package limiter
 
 import (
+	"CloudCamp/internal/config"
+	domain "CloudCamp/internal/domain/limiter"
+	"math"
+	"sort"
 	"sync"
 	"time"
 )
 
-// TokenBucket реализует алгоритм Token Bucket для rate limiting
-type TokenBucket struct {
-	rate   int           // количество токенов
-	per    time.Duration // интервал пополнения токенов
-	tokens int           // текущее количество токенов
-	last   time.Time     // время последнего пополнения
+// bucket лимит одного ключа: настройки и состояние выбранного алгоритма
+type bucket struct {
+	key       string         // ключ бакета
+	rate      int            // количество запросов за период
+	per       time.Duration  // период
+	algorithm string         // имя алгоритма
+	state     limitAlgorithm // состояние алгоритма
+	last      time.Time      // время последнего обновления состояния
 }
 
 // MemoryRateLimiter реализует RateLimiter с хранением в памяти
 type MemoryRateLimiter struct {
-	mu      sync.RWMutex
-	buckets map[string]*TokenBucket
-	clients *ClientSettings
+	mu         sync.RWMutex
+	buckets    map[string]*bucket
+	clients    *ClientSettings
+	algorithm  string             // алгоритм по умолчанию
+	algorithms map[string]string  // алгоритмы, заданные для отдельных клиентов
+	policies   map[string]*bucket // счетчики политик, создаются при первом запросе
 }
 
 // NewMemoryRateLimiter создает новый лимитер с хранением в памяти
 func NewMemoryRateLimiter() *MemoryRateLimiter {
 	return &MemoryRateLimiter{
-		buckets: make(map[string]*TokenBucket),
-		clients: NewClientSettings(0, 0),
+		buckets:    make(map[string]*bucket),
+		clients:    NewClientSettings(0, 0),
+		algorithm:  config.AlgorithmTokenBucket,
+		algorithms: make(map[string]string),
+		policies:   make(map[string]*bucket),
 	}
 }
 
-// Allow проверяет, можно ли пропустить запрос
-func (m *MemoryRateLimiter) Allow(key string) bool {
+// Allow проверяет, можно ли пропустить запрос. Запрос списывается из глобального бакета,
+// бакета клиента и счетчиков политик, только если его допускают все
+func (m *MemoryRateLimiter) Allow(key string, policies ...domain.Limit) domain.Decision {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	now := time.Now()
 
-	// Сначала проверяем глобальный лимит
-	globalBucket, existsGlobal := m.buckets["global"]
-	if existsGlobal {
-		elapsed := now.Sub(globalBucket.last)
-		tokensToAdd := int(elapsed.Seconds() * float64(globalBucket.rate) / globalBucket.per.Seconds())
-
-		// Обновляем токены глобального бакета
-		globalBucket.tokens = minTwoNum(globalBucket.rate, globalBucket.tokens+tokensToAdd)
-		globalBucket.last = now
+	// Применяемые лимиты: глобальный и лимит конкретного клиента. Если у клиента нет бакета, действует только глобальный
+	var applied []*bucket
+	if globalBucket, exists := m.buckets["global"]; exists {
+		applied = append(applied, globalBucket)
+	}
+	if clientBucket, exists := m.buckets[key]; exists && key != "global" {
+		applied = append(applied, clientBucket)
+	}
+	for _, p := range policies {
+		applied = append(applied, m.policyBucket(p, now))
+	}
 
-		// Проверяем глобальный лимит
-		if globalBucket.tokens <= 0 {
-			return false
+	// Отказ описывается лимитом, который освободится позже
+	var denied *bucket
+	for _, b := range applied {
+		if b.state.available(now) < 1 && (denied == nil || b.state.wait(now, 1) > denied.state.wait(now, 1)) {
+			denied = b
 		}
 	}
+	if denied != nil {
+		return denied.decision(false, now)
+	}
 
-	// Затем проверяем лимит конкретного клиента
-	bucket, exists := m.buckets[key]
-	if !exists {
-		// Если у клиента нет бакета и глобальный лимит пройден, разрешаем запрос
-		if existsGlobal {
-			globalBucket.tokens--
+	decision := domain.Decision{Allowed: true}
+	for _, b := range applied {
+		b.take(now)
+		if d := b.decision(true, now); decision.Limit == 0 || d.Remaining < decision.Remaining {
+			decision = d
 		}
-		return true
 	}
+	return decision
+}
 
-	// Вычисляем, сколько токенов нужно добавить
-	elapsed := now.Sub(bucket.last)
-	tokensToAdd := int(elapsed.Seconds() * float64(bucket.rate) / bucket.per.Seconds())
-
-	// Обновляем количество токенов клиента
-	bucket.tokens = minTwoNum(bucket.rate, bucket.tokens+tokensToAdd)
-	bucket.last = now
-
-	// Проверяем, есть ли доступный токен
-	if bucket.tokens > 0 {
-		bucket.tokens--
-		// Если запрос разрешен для клиента, уменьшаем глобальный счетчик
-		if existsGlobal {
-			globalBucket.tokens--
-		}
-		return true
+// policyBucket возвращает счетчик политики, создавая его при первом запросе или при изменении
+// настроек политики. Вызывается под блокировкой
+func (m *MemoryRateLimiter) policyBucket(limit domain.Limit, now time.Time) *bucket {
+	algorithm := limit.Algorithm
+	if algorithm == "" {
+		algorithm = m.algorithm
 	}
 
-	return false
+	b, exists := m.policies[limit.Key]
+	if !exists || b.rate != limit.Rate || b.per != limit.Per || b.algorithm != algorithm {
+		b = newBucket(b, limit.Key, limit.Rate, limit.Per, algorithm, now)
+		m.policies[limit.Key] = b
+	}
+	return b
 }
 
 // GetLimit возвращает текущий лимит для ключа
@@ -100,22 +115,77 @@ func (m *MemoryRateLimiter) SetLimit(key string, rate int, per time.Duration) er
 
 	// Если это глобальный ключ, обновляем дефолтные значения в клиентских настройках
 	if key == "global" {
-		m.clients = NewClientSettings(rate, per)
+		m.clients.SetDefaults(rate, per)
 	}
 
 	// Получаем актуальные настройки для ключа
 	actualRate, actualPer := m.clients.GetSettings(key)
 
-	m.buckets[key] = &TokenBucket{
-		rate:   actualRate,
-		per:    actualPer,
-		tokens: actualRate,
-		last:   time.Now(),
+	m.buckets[key] = newBucket(m.buckets[key], key, actualRate, actualPer, m.algorithmFor(key), time.Now())
+	return nil
+}
+
+// SetAlgorithm устанавливает алгоритм лимита. Для global задается алгоритм по умолчанию, который используют
+// глобальный бакет и клиенты без собственного алгоритма; пустое имя возвращает клиенту алгоритм по умолчанию
+func (m *MemoryRateLimiter) SetAlgorithm(key, name string) error {
+	if key == "global" && name == "" {
+		name = config.AlgorithmTokenBucket
+	}
+	if name != "" {
+		if err := checkAlgorithm(name); err != nil {
+			return err
+		}
 	}
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case key == "global":
+		m.algorithm = name
+	case name == "":
+		delete(m.algorithms, key)
+	default:
+		m.algorithms[key] = name
+	}
+
+	// Переводим существующие бакеты на новый алгоритм. Счетчики политик без собственного алгоритма
+	// переводятся при следующем запросе
+	now := time.Now()
+	for k, b := range m.buckets {
+		if algorithm := m.algorithmFor(k); algorithm != b.algorithm {
+			m.buckets[k] = newBucket(b, k, b.rate, b.per, algorithm, now)
+		}
+	}
 	return nil
 }
 
+// algorithmFor возвращает алгоритм для ключа. Вызывается под блокировкой
+func (m *MemoryRateLimiter) algorithmFor(key string) string {
+	if algorithm, ok := m.algorithms[key]; ok {
+		return algorithm
+	}
+	return m.algorithm
+}
+
+// newBucket создает бакет с новыми настройками. Доступные запросы прежнего бакета old сохраняются,
+// иначе изменение настроек или алгоритма позволяло бы сбросить исчерпанный лимит
+func newBucket(old *bucket, key string, rate int, per time.Duration, algorithm string, now time.Time) *bucket {
+	tokens := float64(rate)
+	if old != nil {
+		tokens = math.Min(tokens, old.state.available(now))
+	}
+
+	return &bucket{
+		key:       key,
+		rate:      rate,
+		per:       per,
+		algorithm: algorithm,
+		state:     newLimitAlgorithm(algorithm, rate, per, tokens, now),
+		last:      now,
+	}
+}
+
 // SetClientLimit устанавливает индивидуальный лимит для клиента
 func (m *MemoryRateLimiter) SetClientLimit(clientID string, rate int, per time.Duration) error {
 	m.clients.SetSettings(clientID, rate, per)
@@ -129,14 +199,89 @@ func (m *MemoryRateLimiter) RemoveClientLimit(clientID string) {
 
 	// Удаляем настройки клиента
 	m.clients.RemoveSettings(clientID)
+	delete(m.algorithms, clientID)
 	// Удаляем bucket клиента
 	delete(m.buckets, clientID)
 }
 
-// minTwoNum возвращает минимальное из двух чисел
-func minTwoNum(a, b int) int {
-	if a < b {
-		return a
+// BucketState снимок состояния бакета на текущий момент
+type BucketState struct {
+	Key       string        // ключ бакета (идентификатор клиента или global)
+	Algorithm string        // алгоритм лимита
+	Rate      int           // количество запросов за период
+	Per       time.Duration // период
+	Tokens    float64       // запросы, доступные к моменту снимка (дробные)
+	Last      time.Time     // время последнего обновления состояния
+}
+
+// Buckets возвращает состояние всех бакетов, не изменяя их
+func (m *MemoryRateLimiter) Buckets() []BucketState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	states := make([]BucketState, 0, len(m.buckets))
+	for key, b := range m.buckets {
+		states = append(states, b.snapshot(key, now))
+	}
+	return states
+}
+
+// Client возвращает состояние бакета клиента с индивидуальным лимитом
+func (m *MemoryRateLimiter) Client(clientID string) (BucketState, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	b, exists := m.buckets[clientID]
+	if !exists || !m.clients.HasCustomSettings(clientID) {
+		return BucketState{}, false
+	}
+	return b.snapshot(clientID, time.Now()), true
+}
+
+// Clients возвращает состояние бакетов всех клиентов с индивидуальными лимитами, упорядоченное по идентификатору
+func (m *MemoryRateLimiter) Clients() []BucketState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	states := make([]BucketState, 0, len(m.buckets))
+	for key, b := range m.buckets {
+		if m.clients.HasCustomSettings(key) {
+			states = append(states, b.snapshot(key, now))
+		}
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Key < states[j].Key })
+
+	return states
+}
+
+// take учитывает пропущенный запрос
+func (b *bucket) take(now time.Time) {
+	b.state.take(now)
+	b.last = now
+}
+
+// decision описывает состояние бакета к моменту now для ответа клиенту
+func (b *bucket) decision(allowed bool, now time.Time) domain.Decision {
+	return domain.Decision{
+		Allowed:    allowed,
+		Key:        b.key,
+		Limit:      b.rate,
+		Remaining:  max(int(b.state.available(now)), 0),
+		Reset:      b.state.wait(now, float64(b.rate)),
+		RetryAfter: b.state.wait(now, 1),
+	}
+}
+
+// snapshot возвращает снимок бакета к моменту now
+func (b *bucket) snapshot(key string, now time.Time) BucketState {
+	return BucketState{
+		Key:       key,
+		Algorithm: b.algorithm,
+		Rate:      b.rate,
+		Per:       b.per,
+		Tokens:    max(b.state.available(now), 0),
+		Last:      b.last,
 	}
-	return b
 }
@@ -0,0 +1,222 @@
+package limiter
+
+import (
+	"CloudCamp/internal/config"
+	domain "CloudCamp/internal/domain/limiter"
+	"CloudCamp/pkg/utils"
+	"crypto/sha256"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// anyIdentity значение условия, которому подходит любой аутентифицированный клиент
+const anyIdentity = "*"
+
+// PolicySet набор политик лимитов с настройками аутентификации клиентов
+type PolicySet struct {
+	policies     []policy
+	apiKeyHeader string
+	apiKeys      map[[sha256.Size]byte]string // SHA-256 ключа -> идентификатор клиента
+	jwtHeader    string
+	jwt          *jwtVerifier
+}
+
+// policy подготовленная политика
+type policy struct {
+	name        string
+	pathPrefix  string
+	methods     map[string]bool
+	hosts       map[string]bool
+	apiKeys     map[string]bool
+	jwtSubjects map[string]bool
+	key         string
+	header      string // заголовок для ключа header:<имя>
+	rate        int
+	per         time.Duration
+	algorithm   string
+}
+
+// Identity клиент, аутентифицированный по API-ключу и JWT. Пустое поле — проверка не пройдена
+type Identity struct {
+	APIKey     string // идентификатор клиента, которому выдан ключ
+	JWTSubject string
+}
+
+// ClientID возвращает идентификатор аутентифицированного клиента: владельца API-ключа, иначе subject JWT.
+// Пустая строка — клиент не аутентифицирован
+func (id Identity) ClientID() string {
+	if id.APIKey != "" {
+		return id.APIKey
+	}
+	return id.JWTSubject
+}
+
+// NewPolicySet создает набор политик по конфигурации. При выключенном rate limiter набор пуст
+func NewPolicySet(cfg config.RateLimitConfig) (*PolicySet, error) {
+	if !cfg.Enabled {
+		return &PolicySet{}, nil
+	}
+
+	jwt, err := newJWTVerifier(cfg.Auth.JWT)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PolicySet{
+		apiKeyHeader: cfg.Auth.APIKeyHeader,
+		apiKeys:      make(map[[sha256.Size]byte]string, len(cfg.Auth.APIKeys)),
+		jwtHeader:    cfg.Auth.JWT.Header,
+		jwt:          jwt,
+	}
+	for clientID, key := range cfg.Auth.APIKeys {
+		s.apiKeys[sha256.Sum256([]byte(key))] = clientID
+	}
+
+	for _, p := range cfg.Policies {
+		compiled := policy{
+			name:        p.Name,
+			pathPrefix:  p.Match.PathPrefix,
+			methods:     toSet(p.Match.Methods, strings.ToUpper),
+			hosts:       toSet(p.Match.Hosts, strings.ToLower),
+			apiKeys:     toSet(p.Match.APIKeys, nil),
+			jwtSubjects: toSet(p.Match.JWTSubjects, nil),
+			key:         p.Key,
+			rate:        p.Rate,
+			per:         p.Period,
+			algorithm:   p.Algorithm,
+		}
+		if strings.HasPrefix(p.Key, config.PolicyKeyHeader) {
+			compiled.key = config.PolicyKeyHeader
+			compiled.header = strings.TrimPrefix(p.Key, config.PolicyKeyHeader)
+		}
+		s.policies = append(s.policies, compiled)
+	}
+
+	return s, nil
+}
+
+// Limits возвращает лимиты политик, подходящих к запросу клиента id. Политика не применяется,
+// если для запроса нельзя определить ее ключ (например, нет проверенного API-ключа)
+func (s *PolicySet) Limits(r *http.Request, id Identity) []domain.Limit {
+	if s == nil || len(s.policies) == 0 {
+		return nil
+	}
+
+	var limits []domain.Limit
+	for _, p := range s.policies {
+		if !p.matches(r, id) {
+			continue
+		}
+		value, ok := p.keyValue(r, id)
+		if !ok {
+			continue
+		}
+		limits = append(limits, domain.Limit{
+			Key:       p.name + ":" + value,
+			Rate:      p.rate,
+			Per:       p.per,
+			Algorithm: p.algorithm,
+		})
+	}
+	return limits
+}
+
+// Identify проверяет API-ключ и JWT запроса
+func (s *PolicySet) Identify(r *http.Request) Identity {
+	var id Identity
+	if s == nil {
+		return id
+	}
+	if len(s.apiKeys) > 0 {
+		if key := r.Header.Get(s.apiKeyHeader); key != "" {
+			id.APIKey = s.apiKeys[sha256.Sum256([]byte(key))]
+		}
+	}
+	if s.jwt != nil {
+		if token, ok := bearerToken(r.Header.Get(s.jwtHeader)); ok {
+			id.JWTSubject, _ = s.jwt.Subject(token, time.Now())
+		}
+	}
+	return id
+}
+
+// matches проверяет условия политики
+func (p *policy) matches(r *http.Request, id Identity) bool {
+	if p.pathPrefix != "" && !strings.HasPrefix(r.URL.Path, p.pathPrefix) {
+		return false
+	}
+	if len(p.methods) > 0 && !p.methods[r.Method] {
+		return false
+	}
+	if len(p.hosts) > 0 && !p.hosts[requestHost(r)] {
+		return false
+	}
+	if len(p.apiKeys) > 0 && !matchIdentity(p.apiKeys, id.APIKey) {
+		return false
+	}
+	if len(p.jwtSubjects) > 0 && !matchIdentity(p.jwtSubjects, id.JWTSubject) {
+		return false
+	}
+	return true
+}
+
+// keyValue возвращает значение ключа счетчика для запроса
+func (p *policy) keyValue(r *http.Request, id Identity) (string, bool) {
+	var value string
+	switch p.key {
+	case config.PolicyKeyIP:
+		value = utils.ClientIP(r)
+	case config.PolicyKeyClientID:
+		value = utils.ClientID(r)
+	case config.PolicyKeyAPIKey:
+		value = id.APIKey
+	case config.PolicyKeyJWTSubject:
+		value = id.JWTSubject
+	case config.PolicyKeyRoute:
+		value = anyIdentity
+	case config.PolicyKeyHeader:
+		value = r.Header.Get(p.header)
+	}
+	return value, value != ""
+}
+
+// matchIdentity проверяет, что клиент аутентифицирован и входит в список
+func matchIdentity(allowed map[string]bool, value string) bool {
+	return value != "" && (allowed[anyIdentity] || allowed[value])
+}
+
+// requestHost возвращает имя хоста запроса без порта в нижнем регистре
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// bearerToken извлекает токен из заголовка вида "Bearer <token>"
+func bearerToken(header string) (string, bool) {
+	const prefix = "Bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(prefix):]), true
+}
+
+// toSet строит множество из списка, при необходимости нормализуя значения
+func toSet(values []string, normalize func(string) string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		if normalize != nil {
+			v = normalize(v)
+		}
+		set[v] = true
+	}
+	return set
+}
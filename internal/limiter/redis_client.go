@@ -0,0 +1,278 @@
+package limiter
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxBulkLen максимальный размер строки в ответе Redis. Ответы лимитера малы,
+// ограничение защищает от выделения памяти под некорректный ответ
+const maxBulkLen = 1 << 20
+
+// RedisError ошибка, возвращенная сервером Redis (ответ вида -ERR ...)
+type RedisError string
+
+// Error возвращает текст ошибки сервера
+func (e RedisError) Error() string {
+	return string(e)
+}
+
+// redisClient минимальный клиент протокола Redis (RESP2) с пулом соединений
+type redisClient struct {
+	addr     string
+	password string
+	db       int
+	timeout  time.Duration
+
+	// slots ограничивает число соединений, занятых запросами. Новое соединение устанавливается, только
+	// если простаивающих нет, поэтому всего соединений (занятых и простаивающих) не больше pool_size
+	slots chan struct{}
+
+	mu     sync.Mutex
+	idle   []*redisConn
+	closed bool
+}
+
+// redisConn одно соединение с сервером
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// newRedisClient создает клиент. Соединения устанавливаются при первых запросах
+func newRedisClient(addr, password string, db int, timeout time.Duration, poolSize int) *redisClient {
+	return &redisClient{
+		addr:     addr,
+		password: password,
+		db:       db,
+		timeout:  timeout,
+		slots:    make(chan struct{}, max(poolSize, 1)),
+	}
+}
+
+// Do выполняет команду и возвращает ответ: string, int64, []any, nil или RedisError
+func (c *redisClient) Do(args ...string) (any, error) {
+	cn, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := cn.do(c.timeout, args...)
+	if err != nil {
+		// После сетевой ошибки состояние соединения неизвестно, поэтому оно не возвращается в пул
+		_ = cn.conn.Close()
+		<-c.slots
+		return nil, err
+	}
+
+	c.put(cn)
+	if redisErr, ok := reply.(RedisError); ok {
+		return nil, redisErr
+	}
+	return reply, nil
+}
+
+// Close закрывает простаивающие соединения. Соединения, занятые запросами, закрываются по их завершении
+func (c *redisClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	for _, cn := range c.idle {
+		_ = cn.conn.Close()
+	}
+	c.idle = nil
+	return nil
+}
+
+// get возвращает соединение из пула или устанавливает новое. Если все pool_size соединений заняты,
+// ждет освобождения одного из них не дольше timeout, чтобы при медленном Redis число соединений не росло
+func (c *redisClient) get() (*redisConn, error) {
+	select {
+	case c.slots <- struct{}{}:
+	default:
+		timer := time.NewTimer(c.timeout)
+		defer timer.Stop()
+		select {
+		case c.slots <- struct{}{}:
+		case <-timer.C:
+			return nil, errors.New("redis connection pool timeout")
+		}
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		<-c.slots
+		return nil, errors.New("redis client is closed")
+	}
+	if n := len(c.idle); n > 0 {
+		cn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return cn, nil
+	}
+	c.mu.Unlock()
+
+	cn, err := c.dial()
+	if err != nil {
+		<-c.slots
+		return nil, err
+	}
+	return cn, nil
+}
+
+// put возвращает соединение в пул или закрывает его, если клиент закрыт, и освобождает место для ожидающих
+func (c *redisClient) put(cn *redisConn) {
+	c.mu.Lock()
+	if c.closed {
+		_ = cn.conn.Close()
+	} else {
+		c.idle = append(c.idle, cn)
+	}
+	c.mu.Unlock()
+	<-c.slots
+}
+
+// dial устанавливает соединение, проходит аутентификацию и выбирает базу
+func (c *redisClient) dial() (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	cn := &redisConn{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		w:    bufio.NewWriter(conn),
+	}
+
+	var setup [][]string
+	if c.password != "" {
+		setup = append(setup, []string{"AUTH", c.password})
+	}
+	if c.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.db)})
+	}
+	for _, args := range setup {
+		reply, err := cn.do(c.timeout, args...)
+		if err == nil {
+			if redisErr, ok := reply.(RedisError); ok {
+				err = redisErr
+			}
+		}
+		if err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("redis %s failed: %w", strings.ToLower(args[0]), err)
+		}
+	}
+
+	return cn, nil
+}
+
+// do отправляет команду в виде массива bulk-строк и читает ответ
+func (cn *redisConn) do(timeout time.Duration, args ...string) (any, error) {
+	if err := cn.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(cn.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(cn.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := cn.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	return readReply(cn.r)
+}
+
+// readReply читает один ответ RESP2
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("malformed redis reply %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return RedisError(payload), nil
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(payload)
+		if err != nil || n > maxBulkLen {
+			return nil, fmt.Errorf("malformed redis bulk length %q", payload)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(payload)
+		if err != nil || n > maxBulkLen {
+			return nil, fmt.Errorf("malformed redis array length %q", payload)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unknown redis reply type %q", kind)
+	}
+}
+
+// redisScript Lua-скрипт, выполняемый через EVALSHA с откатом на EVAL, если скрипт не загружен
+type redisScript struct {
+	src string
+	sha string
+}
+
+// newRedisScript создает скрипт и вычисляет его SHA1
+func newRedisScript(src string) *redisScript {
+	sum := sha1.Sum([]byte(src))
+	return &redisScript{src: src, sha: hex.EncodeToString(sum[:])}
+}
+
+// Run выполняет скрипт атомарно на сервере
+func (s *redisScript) Run(c *redisClient, keys []string, args ...string) (any, error) {
+	cmd := make([]string, 0, 3+len(keys)+len(args))
+	cmd = append(cmd, "EVALSHA", s.sha, strconv.Itoa(len(keys)))
+	cmd = append(cmd, keys...)
+	cmd = append(cmd, args...)
+
+	reply, err := c.Do(cmd...)
+
+	var redisErr RedisError
+	if errors.As(err, &redisErr) && strings.HasPrefix(string(redisErr), "NOSCRIPT") {
+		cmd[0], cmd[1] = "EVAL", s.src
+		return c.Do(cmd...)
+	}
+	return reply, err
+}
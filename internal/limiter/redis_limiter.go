@@ -0,0 +1,201 @@
+package limiter
+
+import (
+	"CloudCamp/internal/config"
+	domain "CloudCamp/internal/domain/limiter"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// errorLogInterval минимальный интервал между сообщениями об ошибках хранилища,
+// чтобы недоступный Redis не заполнял лог записью на каждый запрос
+const errorLogInterval = time.Second
+
+// tokenBucketScript атомарно проверяет бакеты из KEYS и списывает по токену из каждого, только если запрос допускают все.
+// Состояние бакета хранится в хеше {tokens, ts}; токены дробные, поэтому медленное пополнение не теряется.
+// Время берется с сервера Redis, чтобы реплики балансировщика с рассинхронизированными часами считали одинаково.
+//
+// KEYS[i] — бакет, ARGV[2i-1] и ARGV[2i] — его емкость и период (мкс)
+// Возвращает {allowed (1/0), limit, remaining, reset (мкс), retry_after (мкс), i} для бакета i,
+// отклонившего запрос, или для бакета с наименьшим остатком, если запрос пропущен
+var tokenBucketScript = newRedisScript(`
+if redis.replicate_commands then redis.replicate_commands() end
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local buckets = {}
+for i, key in ipairs(KEYS) do
+  local rate, per = tonumber(ARGV[2 * i - 1]), tonumber(ARGV[2 * i])
+  local state = redis.call('HMGET', key, 'tokens', 'ts')
+  local tokens, ts = tonumber(state[1]), tonumber(state[2])
+  if tokens == nil or ts == nil then
+    tokens = rate
+  else
+    tokens = math.min(rate, tokens + math.max(0, now - ts) * rate / per)
+  end
+  buckets[i] = {index = i, key = key, rate = rate, per = per, tokens = tokens}
+end
+
+local function wait(b, tokens)
+  if b.tokens >= tokens then return 0 end
+  return math.ceil((tokens - b.tokens) * b.per / b.rate)
+end
+
+local function reply(allowed, b)
+  return {allowed, b.rate, math.max(0, math.floor(b.tokens)), wait(b, b.rate), wait(b, 1), b.index}
+end
+
+local denied
+for _, b in ipairs(buckets) do
+  if b.tokens < 1 and (denied == nil or wait(b, 1) > wait(denied, 1)) then denied = b end
+end
+if denied then return reply(0, denied) end
+
+local result
+for _, b in ipairs(buckets) do
+  b.tokens = b.tokens - 1
+  redis.call('HSET', b.key, 'tokens', tostring(b.tokens), 'ts', tostring(now))
+  redis.call('PEXPIRE', b.key, math.ceil(b.per / 1000))
+  local r = reply(1, b)
+  if result == nil or r[3] < result[3] then result = r end
+end
+return result
+`)
+
+// LimitSource источник настроек лимитов для распределенного лимитера
+type LimitSource interface {
+	GetLimit(key string) (int, time.Duration)
+	SetLimit(key string, rate int, per time.Duration) error
+}
+
+// RedisRateLimiter реализует RateLimiter с хранением бакетов в Redis, общем для всех реплик балансировщика.
+// Настройки лимитов берутся из локального источника (конфигурация и admin API каждой реплики),
+// а состояние бакетов меняется атомарно Lua-скриптом на сервере
+type RedisRateLimiter struct {
+	client   *redisClient
+	limits   LimitSource
+	prefix   string
+	failOpen bool
+	timeout  time.Duration // таймаут операций с Redis, через него клиенту предлагается повторить запрос при отказе хранилища
+	lastLog  atomic.Int64  // время последнего сообщения об ошибке (UnixNano)
+}
+
+// NewRedisRateLimiter создает распределенный лимитер
+func NewRedisRateLimiter(cfg config.RedisConfig, limits LimitSource) *RedisRateLimiter {
+	return &RedisRateLimiter{
+		client:   newRedisClient(cfg.Addr, cfg.Password, cfg.DB, cfg.Timeout, cfg.PoolSize),
+		limits:   limits,
+		prefix:   cfg.KeyPrefix,
+		failOpen: cfg.FailPolicy != config.FailPolicyClosed,
+		timeout:  cfg.Timeout,
+	}
+}
+
+// Allow проверяет, можно ли пропустить запрос. При недоступности Redis решение
+// принимается по политике fail_policy
+func (l *RedisRateLimiter) Allow(key string, policies ...domain.Limit) domain.Decision {
+	var names, keys, args []string
+	add := func(name, redisKey string, rate int, per time.Duration) {
+		if rate <= 0 {
+			return
+		}
+		names = append(names, name)
+		keys = append(keys, l.prefix+redisKey)
+		args = append(args, strconv.Itoa(rate), formatMicros(per))
+	}
+
+	globalRate, globalPer := l.limits.GetLimit("global")
+	add("global", "global", globalRate, globalPer)
+	if key != "global" {
+		clientRate, clientPer := l.limits.GetLimit(key)
+		add(key, "client:"+key, clientRate, clientPer)
+	}
+	for _, p := range policies {
+		add(p.Key, "policy:"+p.Key, p.Rate, p.Per)
+	}
+
+	// Лимитов нет — обращаться к хранилищу незачем
+	if len(keys) == 0 {
+		return domain.Decision{Allowed: true}
+	}
+
+	reply, err := tokenBucketScript.Run(l.client, keys, args...)
+	if err == nil {
+		var decision domain.Decision
+		if decision, err = parseDecision(reply, names); err == nil {
+			return decision
+		}
+	}
+
+	l.logError(key, err)
+	if l.failOpen {
+		return domain.Decision{Allowed: true}
+	}
+	return domain.Decision{Key: key, RetryAfter: l.timeout}
+}
+
+// parseDecision разбирает ответ скрипта {allowed, limit, remaining, reset, retry_after, index}
+func parseDecision(reply any, names []string) (domain.Decision, error) {
+	items, ok := reply.([]any)
+	if !ok || len(items) != 6 {
+		return domain.Decision{}, fmt.Errorf("unexpected script reply %v", reply)
+	}
+
+	values := make([]int64, len(items))
+	for i, item := range items {
+		if values[i], ok = item.(int64); !ok {
+			return domain.Decision{}, fmt.Errorf("unexpected script reply %v", reply)
+		}
+	}
+	if values[5] < 1 || values[5] > int64(len(names)) {
+		return domain.Decision{}, fmt.Errorf("unexpected bucket index in script reply %v", reply)
+	}
+
+	return domain.Decision{
+		Allowed:    values[0] == 1,
+		Key:        names[values[5]-1],
+		Limit:      int(values[1]),
+		Remaining:  int(values[2]),
+		Reset:      time.Duration(values[3]) * time.Microsecond,
+		RetryAfter: time.Duration(values[4]) * time.Microsecond,
+	}, nil
+}
+
+// GetLimit возвращает текущий лимит для ключа
+func (l *RedisRateLimiter) GetLimit(key string) (int, time.Duration) {
+	return l.limits.GetLimit(key)
+}
+
+// SetLimit устанавливает лимит для ключа
+func (l *RedisRateLimiter) SetLimit(key string, rate int, per time.Duration) error {
+	return l.limits.SetLimit(key, rate, per)
+}
+
+// Close закрывает соединения с Redis
+func (l *RedisRateLimiter) Close() error {
+	return l.client.Close()
+}
+
+// logError логирует ошибку хранилища не чаще errorLogInterval
+func (l *RedisRateLimiter) logError(key string, err error) {
+	now := time.Now().UnixNano()
+	last := l.lastLog.Load()
+	if now-last < int64(errorLogInterval) || !l.lastLog.CompareAndSwap(last, now) {
+		return
+	}
+
+	slog.Error("rate limiter store is unavailable",
+		slog.String("client_id", key),
+		slog.Bool("fail_open", l.failOpen),
+		slog.String("error", err.Error()),
+	)
+}
+
+// formatMicros переводит период в микросекунды для скрипта
+func formatMicros(d time.Duration) string {
+	return strconv.FormatInt(d.Microseconds(), 10)
+}
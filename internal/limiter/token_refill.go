@@ -5,22 +5,28 @@ import (
 	"time"
 )
 
-// RefillAll пополняет токены во всех бакетах
+// RefillAll фиксирует пополнение во всех бакетах и освобождает устаревшее состояние алгоритмов.
+// Доступные запросы вычисляются и без периодического пополнения, поэтому его интервал не влияет на лимиты
 func (m *MemoryRateLimiter) RefillAll() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	now := time.Now()
 	for _, bucket := range m.buckets {
-		slog.Debug("Refill bucket", bucket) // TODO: для мониторинога работы токенов и refill
+		slog.Debug("Refill bucket", slog.Any("bucket", bucket))
 		if bucket == nil {
 			continue
 		}
 
-		elapsed := now.Sub(bucket.last)
-		tokensToAdd := int(elapsed.Seconds() * float64(bucket.rate) / bucket.per.Seconds())
-
-		bucket.tokens = minTwoNum(bucket.rate, bucket.tokens+tokensToAdd)
+		bucket.state.compact(now)
 		bucket.last = now
 	}
+
+	// Полностью восстановленный счетчик политики не отличается от нового, поэтому его можно удалить:
+	// иначе счетчики по IP и другим произвольным ключам копились бы бесконечно
+	for key, bucket := range m.policies {
+		if bucket.state.available(now) >= float64(bucket.rate) {
+			delete(m.policies, key)
+		}
+	}
 }
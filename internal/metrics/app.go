@@ -0,0 +1,231 @@
+package metrics
+
+import (
+	"CloudCamp/internal/domain/balancerDomain"
+	"CloudCamp/internal/limiter"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Метрики прокси
+var (
+	ProxyRequests = NewCounterVec(
+		"cloudcamp_proxy_requests_total",
+		"Total number of proxied requests by backend, status class and method.",
+		"backend", "code", "method",
+	)
+	ProxyDuration = NewHistogramVec(
+		"cloudcamp_proxy_request_duration_seconds",
+		"Latency of proxied requests by backend and method.",
+		DefBuckets,
+		"backend", "method",
+	)
+)
+
+// Метрики rate limiter
+var (
+	RateLimitDecisions = NewCounterVec(
+		"cloudcamp_ratelimit_decisions_total",
+		"Rate limiter decisions by client and result.",
+		"client", "decision",
+	)
+)
+
+// Метрики ограничения одновременных запросов
+var (
+	ConcurrencyRejected = NewCounterVec(
+		"cloudcamp_concurrency_rejected_total",
+		"Requests rejected by the concurrency limiter by scope (client or backend).",
+		"scope",
+	)
+)
+
+// Значения метки scope для отклоненных запросов
+const (
+	ConcurrencyClient  = "client"  // превышен лимит одновременных запросов клиента
+	ConcurrencyBackend = "backend" // все доступные бэкенды заняты
+)
+
+// Метрики проверок здоровья
+var (
+	HealthChecks = NewCounterVec(
+		"cloudcamp_health_checks_total",
+		"Active health checks by backend and result.",
+		"backend", "result",
+	)
+	HealthCheckDuration = NewHistogramVec(
+		"cloudcamp_health_check_duration_seconds",
+		"Duration of active health checks by backend.",
+		DefBuckets,
+		"backend",
+	)
+)
+
+// NoBackend значение метки backend для запросов, для которых не нашлось бэкенда
+const NoBackend = "none"
+
+// DefaultClient значение метки client для клиентов без индивидуального лимита.
+// Ключи таких клиентов (IP или произвольный X-Client-ID) не попадают в метки, чтобы не раздувать их число
+const DefaultClient = "default"
+
+// ObserveProxy учитывает одну попытку проксирования. status равен 0, если бэкенд не ответил
+func ObserveProxy(backend, method string, status int, duration time.Duration) {
+	ProxyRequests.Inc(backend, StatusClass(status), method)
+	if backend != NoBackend {
+		ProxyDuration.Observe(duration.Seconds(), backend, method)
+	}
+}
+
+// ObserveRateLimit учитывает решение rate limiter
+func ObserveRateLimit(client string, allowed bool) {
+	decision := "deny"
+	if allowed {
+		decision = "allow"
+	}
+	RateLimitDecisions.Inc(client, decision)
+}
+
+// ObserveConcurrencyRejected учитывает запрос, отклоненный из-за лимита одновременных запросов
+func ObserveConcurrencyRejected(scope string) {
+	ConcurrencyRejected.Inc(scope)
+}
+
+// ObserveHealthCheck учитывает результат активной проверки здоровья
+func ObserveHealthCheck(backend string, healthy bool, duration time.Duration) {
+	result := "failure"
+	if healthy {
+		result = "success"
+	}
+	HealthChecks.Inc(backend, result)
+	HealthCheckDuration.Observe(duration.Seconds(), backend)
+}
+
+// StatusClass возвращает класс статуса ответа (2xx, 5xx) или error, если ответа нет
+func StatusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "error"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// BackendSource источник актуального списка бэкендов
+type BackendSource interface {
+	GetBackends() []*balancerDomain.Backend
+}
+
+// RegisterBackends регистрирует gauge активных соединений, доступности и лимитов бэкендов.
+// Значения берутся из source при каждом сборе, поэтому изменения пула учитываются автоматически
+func RegisterBackends(source BackendSource) {
+	Default.Register(NewGaugeFunc(
+		"cloudcamp_backend_active_connections",
+		"Number of in-flight requests and tunnels per backend.",
+		[]string{"backend"},
+		func() []Sample {
+			backends := source.GetBackends()
+			samples := make([]Sample, 0, len(backends))
+			for _, b := range backends {
+				samples = append(samples, Sample{Labels: []string{b.ID}, Value: float64(b.GetActiveConnections())})
+			}
+			return samples
+		},
+	))
+	Default.Register(NewGaugeFunc(
+		"cloudcamp_backend_up",
+		"Whether the backend is considered alive by health checks (1) or not (0).",
+		[]string{"backend"},
+		func() []Sample {
+			backends := source.GetBackends()
+			samples := make([]Sample, 0, len(backends))
+			for _, b := range backends {
+				samples = append(samples, Sample{Labels: []string{b.ID}, Value: boolValue(b.IsAlive())})
+			}
+			return samples
+		},
+	))
+	Default.Register(NewGaugeFunc(
+		"cloudcamp_backend_concurrency_limit",
+		"Current limit of in-flight requests per backend, including the adaptive limit.",
+		[]string{"backend"},
+		func() []Sample {
+			backends := source.GetBackends()
+			samples := make([]Sample, 0, len(backends))
+			for _, b := range backends {
+				if limit := b.MaxConnections(); limit > 0 {
+					samples = append(samples, Sample{Labels: []string{b.ID}, Value: float64(limit)})
+				}
+			}
+			return samples
+		},
+	))
+}
+
+// BucketSource источник состояния бакетов rate limiter
+type BucketSource interface {
+	Buckets() []limiter.BucketState
+}
+
+// RegisterBuckets регистрирует gauge заполненности и емкости бакетов rate limiter
+func RegisterBuckets(source BucketSource) {
+	Default.Register(NewGaugeFunc(
+		"cloudcamp_ratelimit_bucket_tokens",
+		"Tokens currently available in the rate limiter bucket.",
+		[]string{"client"},
+		func() []Sample {
+			buckets := source.Buckets()
+			samples := make([]Sample, 0, len(buckets))
+			for _, b := range buckets {
+				samples = append(samples, Sample{Labels: []string{b.Key}, Value: float64(b.Tokens)})
+			}
+			return samples
+		},
+	))
+	Default.Register(NewGaugeFunc(
+		"cloudcamp_ratelimit_bucket_capacity",
+		"Capacity of the rate limiter bucket.",
+		[]string{"client"},
+		func() []Sample {
+			buckets := source.Buckets()
+			samples := make([]Sample, 0, len(buckets))
+			for _, b := range buckets {
+				samples = append(samples, Sample{Labels: []string{b.Key}, Value: float64(b.Rate)})
+			}
+			return samples
+		},
+	))
+}
+
+// Handler возвращает обработчик /metrics для реестра по умолчанию
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+// AdmissionQueueSource источник состояния очереди запросов, ожидающих бэкенд
+type AdmissionQueueSource interface {
+	Stats() limiter.AdmissionStats
+}
+
+// RegisterAdmissionQueue регистрирует gauge глубины очереди по классам приоритета
+func RegisterAdmissionQueue(source AdmissionQueueSource) {
+	Default.Register(NewGaugeFunc(
+		"cloudcamp_admission_queue_depth",
+		"Requests waiting for a free backend by priority class.",
+		[]string{"class"},
+		func() []Sample {
+			stats := source.Stats()
+			samples := make([]Sample, 0, len(stats.Classes))
+			for _, c := range stats.Classes {
+				samples = append(samples, Sample{Labels: []string{c.Class}, Value: float64(c.Queued)})
+			}
+			return samples
+		},
+	))
+}
+
+// boolValue переводит флаг в значение gauge
+func boolValue(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// contentType тип содержимого текстового формата экспозиции Prometheus
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Collector источник метрик, умеющий записать себя в текстовом формате Prometheus
+type Collector interface {
+	Name() string            // имя семейства метрик
+	Write(w io.Writer) error // записывает HELP, TYPE и значения
+}
+
+// Registry набор коллекторов, отдаваемых по /metrics
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]Collector
+}
+
+// NewRegistry создает пустой реестр метрик
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]Collector)}
+}
+
+// Default реестр, в котором регистрируются метрики приложения
+var Default = NewRegistry()
+
+// Register добавляет коллектор. Коллектор с тем же именем заменяется
+func (r *Registry) Register(c Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors[c.Name()] = c
+}
+
+// Write записывает все метрики реестра в порядке имен
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	collectors := make([]Collector, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		collectors = append(collectors, r.collectors[name])
+	}
+	r.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		if err := c.Write(bw); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Handler возвращает HTTP-обработчик, отдающий метрики реестра
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		if err := r.Write(w); err != nil {
+			slog.Warn("failed to write metrics", slog.String("error", err.Error()))
+		}
+	})
+}
+
+// writeHeader записывает строки HELP и TYPE семейства метрик
+func writeHeader(w io.Writer, name, help, typ string) error {
+	_, err := io.WriteString(w, "# HELP "+name+" "+escapeHelp(help)+"\n# TYPE "+name+" "+typ+"\n")
+	return err
+}
+
+// writeSample записывает одно значение метрики с метками
+func writeSample(w io.Writer, name string, labelNames, labelValues []string, extra string, value float64) error {
+	var sb strings.Builder
+	sb.WriteString(name)
+
+	if len(labelNames) > 0 || extra != "" {
+		sb.WriteByte('{')
+		for i, label := range labelNames {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(label)
+			sb.WriteString(`="`)
+			sb.WriteString(escapeLabel(labelValues[i]))
+			sb.WriteByte('"')
+		}
+		if extra != "" {
+			if len(labelNames) > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(extra)
+		}
+		sb.WriteByte('}')
+	}
+
+	sb.WriteByte(' ')
+	sb.WriteString(formatFloat(value))
+	sb.WriteByte('\n')
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// formatFloat форматирует число в представлении Prometheus
+func formatFloat(v float64) string {
+	switch {
+	case v != v:
+		return "NaN"
+	case v > 1.7976931348623157e308:
+		return "+Inf"
+	case v < -1.7976931348623157e308:
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// escapeLabel экранирует значение метки
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// escapeHelp экранирует текст описания метрики
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,229 @@
+package metrics
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// series значения одной комбинации меток
+type series struct {
+	labels  []string
+	value   float64   // значение счетчика или gauge
+	buckets []float64 // счетчики попаданий в корзины гистограммы (не накопительные)
+	sum     float64   // сумма наблюдений гистограммы
+	count   float64   // число наблюдений гистограммы
+}
+
+// vec общая часть семейств метрик с метками
+type vec struct {
+	mu         sync.Mutex
+	name       string
+	help       string
+	labelNames []string
+	series     map[string]*series
+}
+
+// newVec создает семейство метрик
+func newVec(name, help string, labelNames []string) vec {
+	return vec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		series:     make(map[string]*series),
+	}
+}
+
+// Name возвращает имя семейства метрик
+func (v *vec) Name() string {
+	return v.name
+}
+
+// get возвращает серию для значений меток, создавая ее при необходимости (вызывается под mu)
+func (v *vec) get(labelValues []string, buckets int) *series {
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{
+			labels:  append([]string(nil), labelValues...),
+			buckets: make([]float64, buckets),
+		}
+		v.series[key] = s
+	}
+	return s
+}
+
+// sorted возвращает серии, упорядоченные по значениям меток (вызывается под mu)
+func (v *vec) sorted() []*series {
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make([]*series, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, v.series[k])
+	}
+	return out
+}
+
+// CounterVec монотонно растущий счетчик с метками
+type CounterVec struct {
+	vec
+}
+
+// NewCounterVec создает счетчик и регистрирует его в реестре по умолчанию
+func NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{vec: newVec(name, help, labelNames)}
+	Default.Register(c)
+	return c
+}
+
+// Add увеличивает счетчик для значений меток
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.get(labelValues, 0).value += delta
+}
+
+// Inc увеличивает счетчик на единицу
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Value возвращает текущее значение счетчика
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.series[strings.Join(labelValues, "\xff")]; ok {
+		return s.value
+	}
+	return 0
+}
+
+// Write записывает счетчик в текстовом формате
+func (c *CounterVec) Write(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := writeHeader(w, c.name, c.help, "counter"); err != nil {
+		return err
+	}
+	for _, s := range c.sorted() {
+		if err := writeSample(w, c.name, c.labelNames, s.labels, "", s.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DefBuckets границы корзин гистограммы задержек по умолчанию (в секундах)
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// HistogramVec гистограмма с метками
+type HistogramVec struct {
+	vec
+	bounds []float64
+}
+
+// NewHistogramVec создает гистограмму и регистрирует ее в реестре по умолчанию
+func NewHistogramVec(name, help string, bounds []float64, labelNames ...string) *HistogramVec {
+	h := &HistogramVec{vec: newVec(name, help, labelNames), bounds: bounds}
+	Default.Register(h)
+	return h
+}
+
+// Observe добавляет наблюдение для значений меток
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.get(labelValues, len(h.bounds))
+	for i, bound := range h.bounds {
+		if value <= bound {
+			s.buckets[i]++
+			break
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// Write записывает гистограмму в текстовом формате: накопительные корзины, сумму и количество
+func (h *HistogramVec) Write(w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := writeHeader(w, h.name, h.help, "histogram"); err != nil {
+		return err
+	}
+	for _, s := range h.sorted() {
+		var cumulative float64
+		for i, bound := range h.bounds {
+			cumulative += s.buckets[i]
+			if err := writeSample(w, h.name+"_bucket", h.labelNames, s.labels, `le="`+formatFloat(bound)+`"`, cumulative); err != nil {
+				return err
+			}
+		}
+		if err := writeSample(w, h.name+"_bucket", h.labelNames, s.labels, `le="+Inf"`, s.count); err != nil {
+			return err
+		}
+		if err := writeSample(w, h.name+"_sum", h.labelNames, s.labels, "", s.sum); err != nil {
+			return err
+		}
+		if err := writeSample(w, h.name+"_count", h.labelNames, s.labels, "", s.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sample значение gauge с метками, вычисленное в момент сбора
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// GaugeFunc gauge, значения которого вычисляются при каждом сборе метрик
+type GaugeFunc struct {
+	name       string
+	help       string
+	labelNames []string
+	collect    func() []Sample
+}
+
+// NewGaugeFunc создает gauge с вычисляемыми значениями. Регистрируется вызывающим кодом,
+// так как источник значений (бэкенды, бакеты) появляется только при создании сервера
+func NewGaugeFunc(name, help string, labelNames []string, collect func() []Sample) *GaugeFunc {
+	return &GaugeFunc{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		collect:    collect,
+	}
+}
+
+// Name возвращает имя семейства метрик
+func (g *GaugeFunc) Name() string {
+	return g.name
+}
+
+// Write записывает gauge в текстовом формате
+func (g *GaugeFunc) Write(w io.Writer) error {
+	if err := writeHeader(w, g.name, g.help, "gauge"); err != nil {
+		return err
+	}
+
+	samples := g.collect()
+	sort.Slice(samples, func(i, j int) bool {
+		return strings.Join(samples[i].Labels, "\xff") < strings.Join(samples[j].Labels, "\xff")
+	})
+	for _, s := range samples {
+		if err := writeSample(w, g.name, g.labelNames, s.Labels, "", s.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
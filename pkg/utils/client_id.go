@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+)
+
+// authenticatedKey ключ контекста запроса, в котором хранится идентификатор аутентифицированного клиента
+type authenticatedKey struct{}
+
+// ClientID возвращает идентификатор клиента для глобального и клиентских лимитов: заголовок X-Client-ID,
+// при его отсутствии — IP-адрес. Заголовок задает сам клиент, поэтому там, где подмена недопустима,
+// используется TrustedClientID
+func ClientID(r *http.Request) string {
+	if clientID := r.Header.Get("X-Client-ID"); clientID != "" {
+		return clientID
+	}
+	return ClientIP(r)
+}
+
+// WithAuthenticatedClient сохраняет в контексте запроса идентификатор клиента,
+// аутентифицированного по API-ключу или JWT (пусто — клиент не аутентифицирован)
+func WithAuthenticatedClient(r *http.Request, clientID string) *http.Request {
+	if clientID == "" {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), authenticatedKey{}, clientID))
+}
+
+// AuthenticatedClient возвращает идентификатор аутентифицированного клиента или пустую строку
+func AuthenticatedClient(r *http.Request) string {
+	clientID, _ := r.Context().Value(authenticatedKey{}).(string)
+	return clientID
+}
+
+// TrustedClientID возвращает ключ клиента, который нельзя подменить заголовком:
+// идентификатор аутентифицированного клиента, иначе IP-адрес
+func TrustedClientID(r *http.Request) string {
+	if clientID := AuthenticatedClient(r); clientID != "" {
+		return clientID
+	}
+	return ClientIP(r)
+}
@@ -0,0 +1,249 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// clientIPKey ключ контекста запроса, в котором хранится определенный адрес клиента
+type clientIPKey struct{}
+
+// clientAddr адрес клиента, определенный ClientIPResolver
+type clientAddr struct {
+	ip       string
+	viaProxy bool // запрос пришел от доверенного прокси
+}
+
+// Заголовки, из которых берется адрес клиента за доверенным прокси
+const (
+	ForwardedHeaderXFF       = "xff"       // X-Forwarded-For: прокси дописывает адрес соединения в конец
+	ForwardedHeaderForwarded = "forwarded" // Forwarded (RFC 7239), параметры for
+	ForwardedHeaderRealIP    = "x-real-ip" // X-Real-IP: прокси заменяет заголовок адресом соединения
+)
+
+// ForwardedHeaders допустимые значения заголовка с адресом клиента
+var ForwardedHeaders = []string{ForwardedHeaderXFF, ForwardedHeaderForwarded, ForwardedHeaderRealIP}
+
+// ClientIPResolver определяет IP-адрес клиента с учетом доверенных прокси.
+// Адрес берется из одного заголовка, который заполняют доверенные прокси, и только если запрос
+// пришел от доверенного прокси, иначе адресом клиента считается адрес соединения. Остальные заголовки
+// игнорируются: прокси обычно передает их от клиента без изменений
+type ClientIPResolver struct {
+	trusted []*net.IPNet
+	header  string
+}
+
+// NewClientIPResolver создает resolver по списку доверенных прокси (CIDR или отдельные адреса)
+// и заголовку с адресом клиента (пусто — X-Forwarded-For)
+func NewClientIPResolver(trustedProxies []string, header string) (*ClientIPResolver, error) {
+	if header == "" {
+		header = ForwardedHeaderXFF
+	}
+	if !slices.Contains(ForwardedHeaders, header) {
+		return nil, fmt.Errorf("unknown forwarded header %q", header)
+	}
+
+	r := &ClientIPResolver{header: header}
+	for _, proxy := range trustedProxies {
+		network, err := ParseNetwork(proxy)
+		if err != nil {
+			return nil, err
+		}
+		r.trusted = append(r.trusted, network)
+	}
+	return r, nil
+}
+
+// ParseNetwork разбирает CIDR или отдельный IP-адрес (как сеть из одного адреса)
+func ParseNetwork(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", s)
+		}
+		return network, nil
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", s)
+	}
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// Resolve возвращает IP-адрес клиента. Цепочка прокси из выбранного заголовка (X-Forwarded-For или Forwarded)
+// просматривается справа налево: доверенные адреса пропускаются, первый недоверенный считается клиентом.
+// Если вся цепочка состоит из доверенных адресов, клиентом считается самый левый
+func (c *ClientIPResolver) Resolve(r *http.Request) string {
+	peer := parseNode(r.RemoteAddr)
+	if peer == nil {
+		return remoteHost(r.RemoteAddr)
+	}
+	if !c.isTrusted(peer) {
+		return peer.String()
+	}
+
+	var chain []string
+	switch c.header {
+	case ForwardedHeaderRealIP:
+		if ip := parseNode(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+			return ip.String()
+		}
+		return peer.String()
+	case ForwardedHeaderForwarded:
+		chain = forwardedFor(r.Header.Values("Forwarded"))
+	default:
+		chain = xForwardedFor(r.Header.Values("X-Forwarded-For"))
+	}
+
+	client := peer
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := parseNode(chain[i])
+		if ip == nil {
+			// Нераспознанный узел (unknown, обфусцированное имя): дальше цепочке доверять нельзя
+			break
+		}
+		client = ip
+		if !c.isTrusted(ip) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// TrustedPeer проверяет, что запрос пришел от доверенного прокси, то есть его заголовкам можно верить
+func (c *ClientIPResolver) TrustedPeer(r *http.Request) bool {
+	peer := parseNode(r.RemoteAddr)
+	return peer != nil && c.isTrusted(peer)
+}
+
+// isTrusted проверяет, что адрес принадлежит доверенному прокси
+func (c *ClientIPResolver) isTrusted(ip net.IP) bool {
+	if c == nil {
+		return false
+	}
+	for _, network := range c.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithClientIP сохраняет IP-адрес клиента в контексте запроса. viaProxy — запрос пришел от доверенного прокси
+func WithClientIP(r *http.Request, ip string, viaProxy bool) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), clientIPKey{}, clientAddr{ip: ip, viaProxy: viaProxy}))
+}
+
+// FromTrustedProxy проверяет, что запрос пришел от доверенного прокси. Если адрес клиента
+// не определялся ClientIPResolver, прокси не считается доверенным
+func FromTrustedProxy(r *http.Request) bool {
+	addr, _ := r.Context().Value(clientIPKey{}).(clientAddr)
+	return addr.viaProxy
+}
+
+// ClientIP возвращает IP-адрес клиента, определенный ClientIPResolver. Если адрес не определялся,
+// возвращает адрес соединения: заголовкам, которые может подставить клиент, не доверяем
+func ClientIP(r *http.Request) string {
+	if addr, ok := r.Context().Value(clientIPKey{}).(clientAddr); ok {
+		return addr.ip
+	}
+
+	if ip := parseNode(r.RemoteAddr); ip != nil {
+		return ip.String()
+	}
+	return remoteHost(r.RemoteAddr)
+}
+
+// xForwardedFor возвращает узлы цепочки прокси из значений X-Forwarded-For
+func xForwardedFor(values []string) []string {
+	var chain []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			if element = strings.TrimSpace(element); element != "" {
+				chain = append(chain, element)
+			}
+		}
+	}
+	return chain
+}
+
+// forwardedFor возвращает узлы цепочки прокси из параметров for заголовка Forwarded.
+// Элемент без параметра for попадает в цепочку пустым узлом
+func forwardedFor(values []string) []string {
+	var chain []string
+	for _, value := range values {
+		for _, element := range splitQuoted(value, ',') {
+			if strings.TrimSpace(element) == "" {
+				continue
+			}
+
+			node := ""
+			for _, pair := range splitQuoted(element, ';') {
+				key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(strings.TrimSpace(key), "for") {
+					node = strings.Trim(strings.TrimSpace(val), `"`)
+					break
+				}
+			}
+			chain = append(chain, node)
+		}
+	}
+	return chain
+}
+
+// splitQuoted разделяет строку по разделителю, не учитывая разделители внутри кавычек
+func splitQuoted(s string, sep byte) []string {
+	var (
+		parts  []string
+		quoted bool
+		start  int
+	)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseNode разбирает адрес узла: IPv4, IPv6, в том числе в квадратных скобках, с портом или без.
+// Зона IPv6 (fe80::1%eth0) отбрасывается. Для нераспознанного узла возвращает nil
+func parseNode(node string) net.IP {
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		node = host
+	} else {
+		node = strings.TrimSuffix(strings.TrimPrefix(node, "["), "]")
+	}
+	if i := strings.IndexByte(node, '%'); i >= 0 {
+		node = node[:i]
+	}
+
+	ip := net.ParseIP(node)
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
+
+// remoteHost возвращает адрес соединения без порта, если его не удалось разобрать как IP (например, unix-сокет)
+func remoteHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
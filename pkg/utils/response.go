@@ -23,3 +23,11 @@ func SendJSON(w http.ResponseWriter, code int, message string) {
 
 	_ = json.NewEncoder(w).Encode(resp)
 }
+
+// WriteJSON отправляет произвольный объект в виде JSON-ответа
+func WriteJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
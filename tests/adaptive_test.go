@@ -0,0 +1,183 @@
+package tests
+
+import (
+	"CloudCamp/internal/balancer"
+	"CloudCamp/internal/config"
+	"CloudCamp/internal/domain/balancerDomain"
+	"CloudCamp/internal/handler"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestAdaptiveLimitAIMD — проверяет рост лимита при быстрых ответах и уменьшение при ошибках и медленных ответах
+func TestAdaptiveLimitAIMD(t *testing.T) {
+	l := balancerDomain.NewAdaptiveLimit(balancerDomain.AdaptiveLimitSettings{
+		Algorithm:        balancerDomain.LimitAIMD,
+		InitialLimit:     10,
+		MinLimit:         2,
+		MaxLimit:         12,
+		LatencyThreshold: 100 * time.Millisecond,
+		BackoffRatio:     0.5,
+	})
+	assert.Equal(t, 10, l.Limit())
+
+	// При малой загрузке лимит не растет
+	for i := 0; i < 100; i++ {
+		l.Record(false, time.Millisecond, 1)
+	}
+	assert.Equal(t, 10, l.Limit())
+
+	// Под нагрузкой растет примерно на 1 за limit успешных запросов, но не выше max_limit
+	for i := 0; i < 11; i++ {
+		l.Record(false, time.Millisecond, 10)
+	}
+	assert.Equal(t, 11, l.Limit())
+	for i := 0; i < 100; i++ {
+		l.Record(false, time.Millisecond, 12)
+	}
+	assert.Equal(t, 12, l.Limit())
+
+	// Медленный ответ и ошибка уменьшают лимит вдвое, но не ниже min_limit
+	l.Record(false, time.Second, 1)
+	assert.Equal(t, 6, l.Limit())
+	l.Record(true, time.Millisecond, 1)
+	assert.Equal(t, 3, l.Limit())
+	l.Record(true, time.Millisecond, 1)
+	assert.Equal(t, 2, l.Limit())
+}
+
+// TestAdaptiveLimitAIMDBurst — проверяет, что одновременные неудачи уменьшают лимит один раз
+func TestAdaptiveLimitAIMDBurst(t *testing.T) {
+	l := balancerDomain.NewAdaptiveLimit(balancerDomain.AdaptiveLimitSettings{
+		Algorithm:    balancerDomain.LimitAIMD,
+		InitialLimit: 100,
+		BackoffRatio: 0.5,
+	})
+
+	// 20 запросов, выполнявшихся одновременно, завершились ошибкой
+	for i := 20; i > 0; i-- {
+		l.Record(true, time.Minute, int64(i))
+	}
+	assert.Equal(t, 50, l.Limit())
+
+	// Запросы, выполнявшиеся во время уменьшения, завершились: следующая неудача — новое событие
+	l.Record(true, time.Minute, 1)
+	assert.Equal(t, 25, l.Limit())
+
+	// Прошло время ответа: неудача уменьшает лимит, даже если запросы еще выполняются
+	l.Record(true, time.Millisecond, 10)
+	assert.Equal(t, 12, l.Limit())
+	l.Record(true, time.Millisecond, 9)
+	assert.Equal(t, 12, l.Limit())
+	time.Sleep(10 * time.Millisecond)
+	l.Record(true, time.Millisecond, 8)
+	assert.Equal(t, 6, l.Limit())
+}
+
+// TestAdaptiveLimitGradient — проверяет, что лимит растет при стабильной задержке и снижается при ее росте
+func TestAdaptiveLimitGradient(t *testing.T) {
+	l := balancerDomain.NewAdaptiveLimit(balancerDomain.AdaptiveLimitSettings{
+		Algorithm:    balancerDomain.LimitGradient,
+		InitialLimit: 10,
+		MaxLimit:     100,
+	})
+
+	for i := 0; i < 200; i++ {
+		l.Record(false, 10*time.Millisecond, int64(l.Limit()))
+	}
+	grown := l.Limit()
+	assert.Equal(t, 100, grown)
+
+	// Задержка выросла в 10 раз: бэкенд перегружен, лимит снижается
+	for i := 0; i < 20; i++ {
+		l.Record(false, 100*time.Millisecond, int64(l.Limit()))
+	}
+	assert.Less(t, l.Limit(), grown/2)
+
+	// Ошибки снижают лимит независимо от задержки, но не ниже min_limit
+	for i := 0; i < 100; i++ {
+		l.Record(true, time.Millisecond, int64(l.Limit()))
+	}
+	assert.Equal(t, 1, l.Limit())
+	assert.Equal(t, "gradient", l.Algorithm().String())
+}
+
+// TestAdaptiveBackendLimit — проверяет, что адаптивный лимит ограничивает запросы к бэкенду
+// и отражается в admin API
+func TestAdaptiveBackendLimit(t *testing.T) {
+	failing := newTestBackend(t, http.StatusInternalServerError, "error", nil)
+
+	cfg := config.Default()
+	cfg.Concurrency.Enabled = true
+	cfg.Concurrency.PerBackend = 50
+	cfg.Concurrency.Adaptive = config.AdaptiveConfig{
+		Enabled:      true,
+		InitialLimit: 8,
+		MinLimit:     1,
+		BackoffRatio: 0.5,
+	}
+	backend := balancer.NewBackendFromConfig(cfg, config.BackendConfig{URL: failing.URL})
+	assert.Equal(t, 8, backend.MaxConnections())
+
+	rr := balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{backend})
+	proxy := handler.NewProxyHandler(rr, config.ProxyConfig{})
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	assert.Equal(t, 1, backend.MaxConnections())
+
+	var list []handler.BackendResponse
+	assert.Equal(t, http.StatusOK, doAdmin(t, handler.NewBackendHandler(rr, cfg), http.MethodGet, "/admin/backends", "", &list))
+	assert.Equal(t, 1, list[0].AdaptiveLimit)
+	assert.Equal(t, 1, list[0].MaxConnections)
+
+	// Лимит исчерпан: запрос к занятому бэкенду отклоняется
+	assert.True(t, backend.TryIncrementConnections())
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "All backends are busy")
+	backend.DecrementConnections()
+
+	// Статический лимит остается верхней границей, выключение адаптивного лимита возвращает его
+	cfg.Concurrency.Adaptive.Enabled = false
+	balancer.ApplyBackendSettings(cfg, backend)
+	assert.Nil(t, backend.AdaptiveLimit())
+	assert.Equal(t, 50, backend.MaxConnections())
+}
+
+// TestAdaptiveLimitValidation — проверяет ошибки конфигурации адаптивного лимита
+func TestAdaptiveLimitValidation(t *testing.T) {
+	path := writeConfig(t, `
+balancer:
+  backends: ["http://a:1"]
+concurrency:
+  adaptive:
+    enabled: true
+    algorithm: vegas
+    min_limit: 10
+    max_limit: 5
+    backoff_ratio: 1.5
+    tolerance: 0.5
+`)
+
+	_, err := config.LoadConfig(path)
+	var verr *config.ValidationError
+	assert.True(t, errors.As(err, &verr))
+
+	var paths []string
+	for _, p := range verr.Problems {
+		paths = append(paths, p.Path)
+	}
+	assert.ElementsMatch(t, []string{
+		"concurrency.adaptive.algorithm",
+		"concurrency.adaptive.max_limit",
+		"concurrency.adaptive.backoff_ratio",
+		"concurrency.adaptive.tolerance",
+	}, paths)
+}
@@ -0,0 +1,241 @@
+package tests
+
+import (
+	"CloudCamp/internal/app"
+	"CloudCamp/internal/config"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startAdminTestServer запускает сервер с указанными настройками admin listener
+func startAdminTestServer(t *testing.T, port int, admin config.AdminConfig) *app.Server {
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: port},
+		Admin:  admin,
+		Balancer: config.BalancerConfig{
+			Strategy: "round-robin",
+			Backends: []config.BackendConfig{{URL: newTestBackend(t, http.StatusOK, "ok", nil).URL}},
+		},
+		RateLimiter: config.RateLimitConfig{
+			Enabled: true,
+			Rate:    100,
+			Period:  time.Second,
+		},
+	}
+
+	server, err := app.NewServer(cfg)
+	assert.NoError(t, err)
+	go server.Run()
+	t.Cleanup(func() { _ = server.Shutdown() })
+
+	if err = waitForServerReady(fmt.Sprintf("http://localhost:%d", port), 2*time.Second); err != nil {
+		t.Fatalf("Server did not start in time: %v", err)
+	}
+	return server
+}
+
+// adminRequest выполняет запрос к admin API с опциональным токеном
+func adminRequest(t *testing.T, client *http.Client, method, url, token, body string) (int, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	assert.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+// readAuditLog возвращает содержимое журнала аудита
+func readAuditLog(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	return string(data)
+}
+
+// TestAdminListenerToken — проверяет перенос управляющих маршрутов на admin listener с bearer-токеном
+func TestAdminListenerToken(t *testing.T) {
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+	server := startAdminTestServer(t, 8085, config.AdminConfig{
+		Addr:     "127.0.0.1:9095",
+		Token:    "secret",
+		AuditLog: auditPath,
+	})
+	adminURL := "http://127.0.0.1:9095"
+	body := `{"client_id":"admin_client","rate":5,"period":"1m"}`
+
+	t.Run("Missing or wrong token is rejected", func(t *testing.T) {
+		code, _ := adminRequest(t, http.DefaultClient, http.MethodPost, adminURL+"/clients", "", body)
+		assert.Equal(t, http.StatusUnauthorized, code)
+
+		code, _ = adminRequest(t, http.DefaultClient, http.MethodPost, adminURL+"/clients", "wrong", body)
+		assert.Equal(t, http.StatusUnauthorized, code)
+
+		rate, _ := server.GetLimiter().GetLimit("admin_client")
+		assert.Equal(t, 0, rate)
+	})
+
+	t.Run("Management routes are not served on the public port", func(t *testing.T) {
+		// Запрос уходит на бэкенд как обычный проксируемый
+		code, resp := adminRequest(t, http.DefaultClient, http.MethodPost, "http://localhost:8085/clients", "secret", body)
+		assert.Equal(t, http.StatusOK, code)
+		assert.True(t, strings.HasPrefix(resp, "ok:"))
+
+		rate, _ := server.GetLimiter().GetLimit("admin_client")
+		assert.Equal(t, 0, rate)
+	})
+
+	t.Run("Valid token is accepted and audited", func(t *testing.T) {
+		code, _ := adminRequest(t, http.DefaultClient, http.MethodPost, adminURL+"/clients", "secret", body)
+		assert.Equal(t, http.StatusCreated, code)
+
+		rate, _ := server.GetLimiter().GetLimit("admin_client")
+		assert.Equal(t, 5, rate)
+
+		code, metricsOut := adminRequest(t, http.DefaultClient, http.MethodGet, adminURL+"/metrics", "secret", "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Contains(t, metricsOut, "cloudcamp_backend_up")
+
+		audit := readAuditLog(t, auditPath)
+		assert.Contains(t, audit, `"principal":"token"`)
+		assert.Contains(t, audit, `"path":"/clients"`)
+		assert.Contains(t, audit, `"status":201`)
+		assert.Contains(t, audit, `admin_client`)
+		// Запросы на чтение не записываются
+		assert.NotContains(t, audit, "/metrics")
+	})
+
+	t.Run("Token is updated on reload", func(t *testing.T) {
+		cfg := *server.GetConfig()
+		cfg.Admin.Token = "rotated"
+		assert.NoError(t, server.ApplyConfig(&cfg))
+
+		code, _ := adminRequest(t, http.DefaultClient, http.MethodGet, adminURL+"/admin/backends", "secret", "")
+		assert.Equal(t, http.StatusUnauthorized, code)
+		code, _ = adminRequest(t, http.DefaultClient, http.MethodGet, adminURL+"/admin/backends", "rotated", "")
+		assert.Equal(t, http.StatusOK, code)
+	})
+}
+
+// TestAdminListenerUnixSocket — проверяет admin listener на unix-сокете
+func TestAdminListenerUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "admin.sock")
+	startAdminTestServer(t, 8086, config.AdminConfig{Addr: "unix:" + socket})
+
+	info, err := os.Stat(socket)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+
+	code, resp := adminRequest(t, client, http.MethodGet, "http://admin/admin/backends", "", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, resp, `"alive":true`)
+}
+
+// TestAdminListenerMTLS — проверяет обязательный клиентский сертификат
+func TestAdminListenerMTLS(t *testing.T) {
+	dir := t.TempDir()
+	caCert, caKey := newTestCert(t, dir, "ca", nil, nil)
+	newTestCert(t, dir, "server", caCert, caKey)
+	newTestCert(t, dir, "client", caCert, caKey)
+
+	auditPath := filepath.Join(dir, "audit.log")
+	startAdminTestServer(t, 8087, config.AdminConfig{
+		Addr: "127.0.0.1:9097",
+		TLS: config.AdminTLSConfig{
+			CertFile:     filepath.Join(dir, "server.pem"),
+			KeyFile:      filepath.Join(dir, "server-key.pem"),
+			ClientCAFile: filepath.Join(dir, "ca.pem"),
+		},
+		AuditLog: auditPath,
+	})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+
+	t.Run("Client without certificate is rejected", func(t *testing.T) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+		code, _ := adminRequest(t, client, http.MethodGet, "https://127.0.0.1:9097/admin/backends", "", "")
+		assert.Equal(t, 0, code)
+	})
+
+	t.Run("Client with certificate is accepted", func(t *testing.T) {
+		clientCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem"))
+		assert.NoError(t, err)
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{clientCert},
+		}}}
+
+		code, _ := adminRequest(t, client, http.MethodPost, "https://127.0.0.1:9097/clients", "", `{"client_id":"mtls","rate":1,"period":"1m"}`)
+		assert.Equal(t, http.StatusCreated, code)
+		assert.Contains(t, readAuditLog(t, auditPath), `"principal":"cert:client"`)
+	})
+}
+
+// newTestCert выпускает сертификат с CommonName name, подписанный parent (или самоподписанный CA),
+// и записывает его и ключ в dir/name.pem и dir/name-key.pem
+func newTestCert(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	var certPEM, keyPEM bytes.Buffer
+	assert.NoError(t, pem.Encode(&certPEM, &pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	assert.NoError(t, pem.Encode(&keyPEM, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, name+".pem"), certPEM.Bytes(), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, name+"-key.pem"), keyPEM.Bytes(), 0600))
+
+	return cert, key
+}
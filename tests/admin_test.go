@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"CloudCamp/internal/balancer"
+	"CloudCamp/internal/config"
+	"CloudCamp/internal/domain/balancerDomain"
+	"CloudCamp/internal/handler"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// doAdmin выполняет запрос к admin-обработчику и декодирует JSON-ответ
+func doAdmin(t *testing.T, h http.Handler, method, path, body string, out any) int {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	if out != nil {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), out))
+	}
+	return rec.Code
+}
+
+// TestBackendAdmin — проверяет добавление, вывод из ротации и удаление бэкендов во время работы
+func TestBackendAdmin(t *testing.T) {
+	rr := balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{
+		balancerDomain.NewBackend("http://backend1:8081", 1),
+	})
+	h := handler.NewBackendHandler(rr, &config.Config{})
+
+	var created handler.BackendResponse
+	code := doAdmin(t, h, http.MethodPost, "/admin/backends", `{"id":"b2","url":"http://backend2:8082","weight":2}`, &created)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "b2", created.ID)
+	assert.Equal(t, 2, created.Weight)
+
+	// Повторное добавление и некорректный URL отклоняются
+	assert.Equal(t, http.StatusConflict, doAdmin(t, h, http.MethodPost, "/admin/backends", `{"url":"http://backend2:8082"}`, nil))
+	assert.Equal(t, http.StatusBadRequest, doAdmin(t, h, http.MethodPost, "/admin/backends", `{"url":"backend3"}`, nil))
+
+	var list []handler.BackendResponse
+	assert.Equal(t, http.StatusOK, doAdmin(t, h, http.MethodGet, "/admin/backends", "", &list))
+	assert.Len(t, list, 2)
+	assert.Equal(t, "backend1:8081", list[0].ID)
+
+	// Выведенный из ротации бэкенд не выбирается стратегией
+	assert.Equal(t, http.StatusOK, doAdmin(t, h, http.MethodPost, "/admin/backends/backend1:8081/drain", "", nil))
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "b2", rr.NextBackend().ID)
+	}
+
+	assert.Equal(t, http.StatusOK, doAdmin(t, h, http.MethodDelete, "/admin/backends/b2", "", nil))
+	assert.Equal(t, http.StatusNotFound, doAdmin(t, h, http.MethodDelete, "/admin/backends/b2", "", nil))
+	assert.Len(t, rr.GetBackends(), 1)
+}
+
+// TestBackendDraining — проверяет, что draining не снимается проверками здоровья и ожидает соединений
+func TestBackendDraining(t *testing.T) {
+	b := balancerDomain.NewBackend("http://backend1:8081", 1)
+	rr := balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{b})
+	h := handler.NewBackendHandler(rr, &config.Config{})
+
+	b.IncrementConnections()
+
+	var resp handler.BackendResponse
+	code := doAdmin(t, h, http.MethodPost, "/admin/backends/backend1:8081/drain?wait=50ms", "", &resp)
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.True(t, resp.Draining)
+	assert.Equal(t, int64(1), resp.ActiveConnections)
+
+	// Успешная проверка здоровья не возвращает бэкенд в ротацию
+	b.ReportSuccess()
+	assert.True(t, b.IsAlive())
+	assert.Nil(t, rr.NextBackend())
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		b.DecrementConnections()
+	}()
+	code = doAdmin(t, h, http.MethodPost, "/admin/backends/backend1:8081/drain?wait=1s", "", &resp)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(0), resp.ActiveConnections)
+
+	assert.Equal(t, http.StatusOK, doAdmin(t, h, http.MethodDelete, "/admin/backends/backend1:8081/drain", "", nil))
+	assert.Same(t, b, rr.NextBackend())
+}
@@ -0,0 +1,350 @@
+package tests
+
+import (
+	"CloudCamp/internal/balancer"
+	"CloudCamp/internal/config"
+	"CloudCamp/internal/domain/balancerDomain"
+	"CloudCamp/internal/handler"
+	"CloudCamp/internal/limiter"
+	"CloudCamp/pkg/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testSlots свободные места на бэкендах для проверки очереди без прокси
+type testSlots struct {
+	mu   sync.Mutex
+	free int
+}
+
+// try занимает место, если оно есть
+func (s *testSlots) try() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.free == 0 {
+		return false
+	}
+	s.free--
+	return true
+}
+
+// add освобождает места
+func (s *testSlots) add(n int) {
+	s.mu.Lock()
+	s.free += n
+	s.mu.Unlock()
+}
+
+// newTestQueue создает очередь с классом premium для клиента vip и классом batch с коротким таймаутом
+func newTestQueue(maxSize int) *limiter.AdmissionQueue {
+	return limiter.NewAdmissionQueue(config.ConcurrencyConfig{
+		Enabled:      true,
+		QueueTimeout: 2 * time.Second,
+		Queue: config.QueueConfig{
+			MaxSize:        maxSize,
+			PriorityHeader: "X-Priority",
+			Classes: []config.PriorityClass{
+				{Name: "premium", Clients: []string{"vip"}},
+				{Name: config.DefaultPriorityClass},
+				{Name: "batch", Timeout: 30 * time.Millisecond},
+			},
+		},
+	})
+}
+
+// queueDepth возвращает число ожидающих запросов класса
+func queueDepth(q *limiter.AdmissionQueue, class string) int {
+	for _, c := range q.Stats().Classes {
+		if c.Class == class {
+			return c.Queued
+		}
+	}
+	return -1
+}
+
+// TestAdmissionQueuePriority — проверяет, что при освобождении места первым допускается более приоритетный класс
+func TestAdmissionQueuePriority(t *testing.T) {
+	q := newTestQueue(0)
+	slots := &testSlots{}
+
+	assert.Equal(t, "premium", q.Class("vip", http.Header{}))
+	assert.Equal(t, "batch", q.Class("vip", http.Header{"X-Priority": {"batch"}}))
+	assert.Equal(t, config.DefaultPriorityClass, q.Class("other", http.Header{"X-Priority": {"unknown"}}))
+
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+	enqueue := func(name, class string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, q.Wait(context.Background(), class, slots.try))
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+		}()
+		assert.Eventually(t, func() bool { return queueDepth(q, class) > 0 }, time.Second, time.Millisecond)
+	}
+
+	enqueue("default-1", config.DefaultPriorityClass)
+	enqueue("default-2", config.DefaultPriorityClass)
+	enqueue("premium", "premium")
+	assert.Equal(t, 3, q.Stats().Queued)
+
+	for i := 0; i < 3; i++ {
+		slots.add(1)
+		q.Notify()
+		assert.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(order) == i+1
+		}, time.Second, time.Millisecond)
+	}
+	wg.Wait()
+
+	assert.Equal(t, []string{"premium", "default-1", "default-2"}, order)
+	assert.Equal(t, 0, q.Stats().Queued)
+
+	// При свободном месте и пустой очереди запрос не ждет
+	slots.add(1)
+	assert.NoError(t, q.Wait(context.Background(), config.DefaultPriorityClass, slots.try))
+}
+
+// TestAdmissionQueueBounded — проверяет ограничение размера очереди и вытеснение менее приоритетных запросов
+func TestAdmissionQueueBounded(t *testing.T) {
+	q := newTestQueue(2)
+	slots := &testSlots{}
+
+	results := make(chan error, 3)
+	for i := 0; i < 2; i++ {
+		go func() { results <- q.Wait(context.Background(), config.DefaultPriorityClass, slots.try) }()
+	}
+	assert.Eventually(t, func() bool { return q.Stats().Queued == 2 }, time.Second, time.Millisecond)
+
+	// Запрос того же класса в переполненную очередь не попадает
+	assert.ErrorIs(t, q.Wait(context.Background(), config.DefaultPriorityClass, slots.try), limiter.ErrQueueFull)
+
+	// Приоритетный запрос вытесняет последний запрос класса default
+	go func() { results <- q.Wait(context.Background(), "premium", slots.try) }()
+	assert.ErrorIs(t, <-results, limiter.ErrQueueFull)
+	assert.Equal(t, 1, queueDepth(q, "premium"))
+	assert.Equal(t, 1, queueDepth(q, config.DefaultPriorityClass))
+
+	slots.add(2)
+	q.Notify()
+	assert.NoError(t, <-results)
+	assert.NoError(t, <-results)
+
+	// Состояние очереди доступно через admin API
+	rec := httptest.NewRecorder()
+	handler.NewQueueHandler(q).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/queue", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp handler.QueueResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	assert.Equal(t, 2, resp.MaxSize)
+	assert.Equal(t, 0, resp.Queued)
+	assert.Equal(t, []handler.QueueClassResponse{
+		{Class: "premium", Timeout: "2s", Admitted: 1},
+		{Class: config.DefaultPriorityClass, Timeout: "2s", Admitted: 1, Rejected: 2},
+		{Class: "batch", Timeout: "30ms"},
+	}, resp.Classes)
+}
+
+// TestAdmissionQueueTimeout — проверяет таймаут класса и отмену ожидания клиентом
+func TestAdmissionQueueTimeout(t *testing.T) {
+	q := newTestQueue(0)
+	slots := &testSlots{}
+
+	start := time.Now()
+	assert.ErrorIs(t, q.Wait(context.Background(), "batch", slots.try), limiter.ErrQueueTimeout)
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	assert.Less(t, time.Since(start), time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, q.Wait(ctx, config.DefaultPriorityClass, slots.try), context.DeadlineExceeded)
+
+	stats := q.Stats()
+	assert.Equal(t, 0, stats.Queued)
+	assert.Equal(t, uint64(1), stats.Classes[1].TimedOut)
+	assert.Equal(t, uint64(1), stats.Classes[2].TimedOut)
+
+	// Без включенного ограничения запросы не ждут
+	disabled := limiter.NewAdmissionQueue(config.ConcurrencyConfig{QueueTimeout: time.Second})
+	assert.ErrorIs(t, disabled.Wait(context.Background(), config.DefaultPriorityClass, slots.try), limiter.ErrQueueTimeout)
+}
+
+// TestAdmissionQueueNoBackend — проверяет, что запрос ждет в очереди возвращения бэкенда в ротацию
+func TestAdmissionQueueNoBackend(t *testing.T) {
+	srv := newTestBackend(t, http.StatusOK, "backend", nil)
+	backend := balancerDomain.NewBackend(srv.URL, 1)
+	backend.SetAlive(false)
+
+	proxy := handler.NewProxyHandler(balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{backend}), config.ProxyConfig{})
+	proxy.SetAdmissionQueue(limiter.NewAdmissionQueue(config.ConcurrencyConfig{Enabled: true, QueueTimeout: time.Second}))
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		backend.SetAlive(true)
+	}()
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+// TestAdmissionQueueValidation — проверяет ошибки конфигурации очереди
+func TestAdmissionQueueValidation(t *testing.T) {
+	path := writeConfig(t, `
+balancer:
+  backends: ["http://a:1"]
+concurrency:
+  queue:
+    max_size: -1
+    classes:
+      - name: premium
+        timeout: -1s
+      - name: premium
+      - clients: [vip]
+`)
+
+	_, err := config.LoadConfig(path)
+	var verr *config.ValidationError
+	assert.True(t, errors.As(err, &verr))
+
+	var paths []string
+	for _, p := range verr.Problems {
+		paths = append(paths, p.Path)
+	}
+	assert.ElementsMatch(t, []string{
+		"concurrency.queue.max_size",
+		"concurrency.queue.classes[0].timeout",
+		"concurrency.queue.classes[1].name",
+		"concurrency.queue.classes[2].name",
+	}, paths)
+}
+
+// TestAdmissionQueueRetry — проверяет, что повторная попытка не ждет в очереди, когда опробованы все бэкенды
+func TestAdmissionQueueRetry(t *testing.T) {
+	var hits atomic.Int64
+	srv := newTestBackend(t, http.StatusServiceUnavailable, "busy", &hits)
+
+	proxy := handler.NewProxyHandler(balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{
+		balancerDomain.NewBackend(srv.URL, 1),
+	}), config.ProxyConfig{Retry: config.RetryConfig{
+		MaxAttempts: 3,
+		StatusCodes: []int{http.StatusServiceUnavailable},
+	}})
+	queue := limiter.NewAdmissionQueue(config.ConcurrencyConfig{Enabled: true, QueueTimeout: time.Second})
+	proxy.SetAdmissionQueue(queue)
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "busy:", rec.Body.String())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, int64(1), hits.Load())
+
+	stats := queue.Stats()
+	assert.Equal(t, 0, stats.Queued)
+	assert.Equal(t, uint64(0), stats.Classes[0].TimedOut)
+}
+
+// TestAdmissionQueuePriorityHeader — проверяет, что заголовок приоритета учитывается только от доверенных прокси
+func TestAdmissionQueuePriorityHeader(t *testing.T) {
+	srv := newTestBackend(t, http.StatusOK, "backend", nil)
+	backend := balancerDomain.NewBackend(srv.URL, 1)
+	backend.SetMaxConnections(1)
+	assert.True(t, backend.TryIncrementConnections())
+	defer backend.DecrementConnections()
+
+	queue := limiter.NewAdmissionQueue(config.ConcurrencyConfig{
+		Enabled:      true,
+		QueueTimeout: 20 * time.Millisecond,
+		Queue: config.QueueConfig{
+			PriorityHeader: "X-Priority",
+			Classes:        []config.PriorityClass{{Name: config.DefaultPriorityClass}, {Name: "batch"}},
+		},
+	})
+	proxy := handler.NewProxyHandler(balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{backend}), config.ProxyConfig{})
+	proxy.SetAdmissionQueue(queue)
+
+	resolver, err := utils.NewClientIPResolver([]string{"10.0.0.0/8"}, "")
+	assert.NoError(t, err)
+	chain := handler.NewClientIPMiddleware(resolver).Middleware(proxy)
+
+	for _, peer := range []string{"192.0.2.1:1234", "10.0.0.5:1234"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = peer
+		req.Header.Set("X-Priority", "batch")
+		rec := httptest.NewRecorder()
+		chain.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	}
+
+	// Запрос напрямую от клиента попал в класс default, запрос от доверенного прокси — в batch
+	stats := queue.Stats()
+	assert.Equal(t, uint64(1), stats.Classes[0].TimedOut)
+	assert.Equal(t, uint64(1), stats.Classes[1].TimedOut)
+}
+
+// fixedStrategy сторонняя стратегия, возвращающая один бэкенд без учета его доступности
+type fixedStrategy struct {
+	balancerDomain.Strategy
+	backend *balancerDomain.Backend
+}
+
+// NextBackend возвращает бэкенд стратегии
+func (s fixedStrategy) NextBackend() *balancerDomain.Backend {
+	return s.backend
+}
+
+// TestAdmissionQueueCircuitBreaker — проверяет, что бэкенд, отклоненный circuit breaker, не исключается
+// из выбора для ожидающего в очереди запроса
+func TestAdmissionQueueCircuitBreaker(t *testing.T) {
+	srv := newTestBackend(t, http.StatusOK, "backend", nil)
+	cb := balancerDomain.NewCircuitBreaker(balancerDomain.CircuitBreakerSettings{
+		MinRequests:      1,
+		Cooldown:         20 * time.Millisecond,
+		HalfOpenRequests: 1,
+	})
+	backend := balancerDomain.NewBackend(srv.URL, 1)
+	backend.SetCircuitBreaker(cb)
+	backend.RecordResult(true, time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+
+	// Пробный слот half-open занят другим запросом
+	assert.True(t, backend.AllowRequest())
+
+	strategy := fixedStrategy{Strategy: balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{backend}), backend: backend}
+	proxy := handler.NewProxyHandler(strategy, config.ProxyConfig{})
+	queue := limiter.NewAdmissionQueue(config.ConcurrencyConfig{Enabled: true, QueueTimeout: time.Second})
+	proxy.SetAdmissionQueue(queue)
+
+	codes := make(chan int, 1)
+	go func() {
+		rec := httptest.NewRecorder()
+		proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		codes <- rec.Code
+	}()
+	assert.Eventually(t, func() bool { return queue.Stats().Queued == 1 }, time.Second, time.Millisecond)
+
+	// Пробный запрос успешен, цепь замкнута: ожидающий запрос допускается
+	backend.RecordResult(false, time.Millisecond)
+	queue.Notify()
+	assert.Equal(t, http.StatusOK, <-codes)
+}
@@ -0,0 +1,202 @@
+package tests
+
+import (
+	"CloudCamp/internal/balancer"
+	"CloudCamp/internal/config"
+	"CloudCamp/internal/domain/balancerDomain"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestWeightedRoundRobin — проверяет плавное взвешенное распределение без пачек
+func TestWeightedRoundRobin(t *testing.T) {
+	a := balancerDomain.NewBackend("http://a", 5)
+	b := balancerDomain.NewBackend("http://b", 1)
+	c := balancerDomain.NewBackend("http://c", 1)
+	wrr := balancer.NewWeightedRoundRobinBalancer([]*balancerDomain.Backend{a, b, c})
+
+	var got []string
+	for i := 0; i < 7; i++ {
+		got = append(got, wrr.NextBackend().URL)
+	}
+
+	// Классическая последовательность nginx для весов 5:1:1
+	assert.Equal(t, []string{"http://a", "http://a", "http://b", "http://a", "http://c", "http://a", "http://a"}, got)
+
+	// Недоступный бэкенд пропускается
+	wrr.MarkBackendDown(a)
+	for i := 0; i < 4; i++ {
+		assert.NotEqual(t, "http://a", wrr.NextBackend().URL)
+	}
+}
+
+// TestConsistentHash — проверяет липкость ключей и перенос только ключей упавшего бэкенда
+func TestConsistentHash(t *testing.T) {
+	backends := []*balancerDomain.Backend{
+		balancerDomain.NewBackend("http://a", 1),
+		balancerDomain.NewBackend("http://b", 1),
+		balancerDomain.NewBackend("http://c", 1),
+	}
+	keyFunc, err := balancer.NewKeyFunc(balancer.KeySourceClientID, "")
+	assert.NoError(t, err)
+	ch := balancer.NewConsistentHashBalancer(backends, keyFunc, 0)
+
+	before := make(map[string]*balancerDomain.Backend)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("user-%d", i)
+		before[key] = ch.NextBackendByKey(key)
+		assert.Same(t, before[key], ch.NextBackendByKey(key))
+	}
+
+	// Запрос с заголовком X-Client-ID попадает туда же, куда и ключ
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Client-ID", "user-42")
+	assert.Same(t, before["user-42"], ch.SelectBackend(&balancerDomain.RoutingContext{Request: req}))
+
+	down := backends[1]
+	ch.MarkBackendDown(down)
+
+	for key, prev := range before {
+		got := ch.NextBackendByKey(key)
+		assert.NotSame(t, down, got)
+		if prev != down {
+			assert.Same(t, prev, got, "key %s should not move", key)
+		}
+	}
+}
+
+// TestRequestStrategyAdapter — проверяет, что стратегии без учета запроса работают через адаптер
+func TestRequestStrategyAdapter(t *testing.T) {
+	a := balancerDomain.NewBackend("http://a", 1)
+	b := balancerDomain.NewBackend("http://b", 1)
+	rr := balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{a, b})
+
+	// Сторонняя стратегия, реализующая только Strategy
+	rs := balancerDomain.AsRequestStrategy(struct{ balancerDomain.Strategy }{rr})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Same(t, b, rs.SelectBackend(&balancerDomain.RoutingContext{Request: req}))
+	assert.Same(t, b, rs.SelectBackend(&balancerDomain.RoutingContext{Request: req, Exclude: []*balancerDomain.Backend{a}}))
+	assert.Nil(t, rs.SelectBackend(&balancerDomain.RoutingContext{Request: req, Exclude: []*balancerDomain.Backend{a, b}}))
+
+	// Стратегия, уже учитывающая запрос, возвращается без обертки
+	keyFunc, _ := balancer.NewKeyFunc(balancer.KeySourceIP, "")
+	ch := balancer.NewConsistentHashBalancer([]*balancerDomain.Backend{a}, keyFunc, 0)
+	assert.Equal(t, balancerDomain.RequestStrategy(ch), balancerDomain.AsRequestStrategy(ch))
+}
+
+// TestSelectBackendExclude — проверяет, что встроенные стратегии пропускают исключенные бэкенды,
+// а повтор запроса не сдвигает очередь Round Robin на пропущенные бэкенды
+func TestSelectBackendExclude(t *testing.T) {
+	a := balancerDomain.NewBackend("http://a", 1)
+	b := balancerDomain.NewBackend("http://b", 1)
+	c := balancerDomain.NewBackend("http://c", 1)
+	backends := []*balancerDomain.Backend{a, b, c}
+	exclude := func(excluded ...*balancerDomain.Backend) *balancerDomain.RoutingContext {
+		return &balancerDomain.RoutingContext{Exclude: excluded}
+	}
+
+	rr := balancer.NewRoundRobinBalancer(backends)
+	assert.Same(t, b, rr.NextBackend())
+	assert.Same(t, a, rr.SelectBackend(exclude(b, c)))
+	assert.Same(t, b, rr.NextBackend())
+	assert.Same(t, c, rr.NextBackend())
+	assert.Nil(t, rr.SelectBackend(exclude(a, b, c)))
+
+	strategies := []balancerDomain.RequestStrategy{
+		balancer.NewWeightedRoundRobinBalancer(backends),
+		balancer.NewLeastConnectionsBalancer(backends),
+		balancer.NewRandomBalancer(backends),
+	}
+	for _, s := range strategies {
+		for i := 0; i < 10; i++ {
+			assert.Same(t, c, s.SelectBackend(exclude(a, b)))
+			assert.NotSame(t, a, s.SelectBackend(exclude(a)))
+		}
+		assert.Nil(t, s.SelectBackend(exclude(a, b, c)))
+	}
+}
+
+// TestNewStrategyErrors — проверяет, что ошибка построения стратегии возвращается, а не теряется
+func TestNewStrategyErrors(t *testing.T) {
+	cfg := &config.Config{Balancer: config.BalancerConfig{Strategy: "fastest"}}
+	_, err := balancer.NewStrategy(cfg, nil)
+	assert.ErrorContains(t, err, "unsupported balancing strategy: fastest")
+
+	cfg.Balancer.Strategy = "consistent-hash"
+	cfg.Balancer.ConsistentHash.KeySource = balancer.KeySourceHeader
+	_, err = balancer.NewStrategy(cfg, nil)
+	assert.ErrorContains(t, err, "requires key name")
+
+	// Неудачная замена стратегии оставляет текущую
+	a := balancerDomain.NewBackend("http://a", 1)
+	sb := balancer.NewSwitchableBalancer(balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{a}))
+	assert.Error(t, sb.Swap(func(backends []*balancerDomain.Backend) (balancerDomain.Strategy, error) {
+		return balancer.NewStrategy(cfg, backends)
+	}))
+	assert.Same(t, a, sb.NextBackend())
+}
+
+// TestBackendHealthThresholds — проверяет пороги rise/fall пассивной проверки здоровья
+func TestBackendHealthThresholds(t *testing.T) {
+	b := balancerDomain.NewBackend("http://a", 1)
+	b.SetHealthThresholds(2, 3)
+
+	// Одиночный сбой, прерванный успехом, не исключает бэкенд
+	assert.False(t, b.ReportFailure())
+	assert.False(t, b.ReportFailure())
+	b.ReportSuccess()
+	assert.False(t, b.ReportFailure())
+	assert.True(t, b.IsAlive())
+
+	assert.False(t, b.ReportFailure())
+	assert.True(t, b.ReportFailure())
+	assert.False(t, b.IsAlive())
+
+	// Возврат только после двух успехов подряд
+	assert.False(t, b.ReportSuccess())
+	assert.False(t, b.IsAlive())
+	assert.True(t, b.ReportSuccess())
+	assert.True(t, b.IsAlive())
+}
+
+// TestCircuitBreaker — проверяет переходы closed -> open -> half-open -> closed
+func TestCircuitBreaker(t *testing.T) {
+	cb := balancerDomain.NewCircuitBreaker(balancerDomain.CircuitBreakerSettings{
+		Window:           time.Second,
+		MinRequests:      4,
+		ErrorRate:        0.5,
+		Cooldown:         50 * time.Millisecond,
+		HalfOpenRequests: 2,
+	})
+	b := balancerDomain.NewBackend("http://a", 1)
+	b.SetCircuitBreaker(cb)
+
+	b.RecordResult(false, time.Millisecond)
+	b.RecordResult(false, time.Millisecond)
+	b.RecordResult(true, time.Millisecond)
+	assert.Equal(t, balancerDomain.CircuitClosed, cb.State())
+	b.RecordResult(true, time.Millisecond)
+
+	// 2 ошибки из 4 запросов — цепь разомкнута, стратегии пропускают бэкенд
+	assert.Equal(t, balancerDomain.CircuitOpen, cb.State())
+	assert.False(t, b.IsAvailable())
+	rr := balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{b})
+	assert.Nil(t, rr.NextBackend())
+
+	// После cooldown пропускается ограниченное число пробных запросов
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, balancerDomain.CircuitHalfOpen, cb.State())
+	assert.True(t, b.AllowRequest())
+	assert.True(t, b.AllowRequest())
+	assert.False(t, b.AllowRequest())
+	assert.False(t, b.IsAvailable())
+
+	b.RecordResult(false, time.Millisecond)
+	b.RecordResult(false, time.Millisecond)
+	assert.Equal(t, balancerDomain.CircuitClosed, cb.State())
+	assert.True(t, b.IsAvailable())
+}
@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"CloudCamp/internal/config"
+	"CloudCamp/internal/handler"
+	"CloudCamp/pkg/utils"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestClientIPResolver — проверяет определение адреса клиента за доверенными прокси
+func TestClientIPResolver(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "::1", "fd00::/8"}
+	resolvers := make(map[string]*utils.ClientIPResolver)
+	for _, header := range utils.ForwardedHeaders {
+		resolver, err := utils.NewClientIPResolver(trusted, header)
+		assert.NoError(t, err)
+		resolvers[header] = resolver
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		remoteAddr string
+		headers    map[string][]string
+		want       string
+	}{
+		{
+			name:       "untrusted peer headers are ignored",
+			header:     utils.ForwardedHeaderXFF,
+			remoteAddr: "203.0.113.7:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.1.1.1"}, "Forwarded": {"for=1.1.1.1"}, "X-Real-IP": {"1.1.1.1"}},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "spoofed entries left of the client are skipped",
+			header:     utils.ForwardedHeaderXFF,
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.1.1.1, 198.51.100.4", "10.0.0.9"}},
+			want:       "198.51.100.4",
+		},
+		{
+			name:       "whole chain trusted",
+			header:     utils.ForwardedHeaderXFF,
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"10.1.1.1, 10.0.0.9"}},
+			want:       "10.1.1.1",
+		},
+		{
+			name:       "unknown hop stops the walk",
+			header:     utils.ForwardedHeaderXFF,
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.1.1.1, unknown, 10.0.0.9"}},
+			want:       "10.0.0.9",
+		},
+		{
+			name:       "client forwarded header is ignored with xff",
+			header:     utils.ForwardedHeaderXFF,
+			remoteAddr: "10.0.0.5:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"203.0.113.9"}, "Forwarded": {"for=1.2.3.4"}},
+			want:       "203.0.113.9",
+		},
+		{
+			name:       "client x-real-ip is ignored with xff",
+			header:     utils.ForwardedHeaderXFF,
+			remoteAddr: "[fd00::5]:443",
+			headers:    map[string][]string{"X-Real-IP": {"1.2.3.4"}},
+			want:       "fd00::5",
+		},
+		{
+			name:       "forwarded header",
+			header:     utils.ForwardedHeaderForwarded,
+			remoteAddr: "[::1]:1234",
+			headers: map[string][]string{
+				"Forwarded":       {`for=1.1.1.1, For="[2001:db8:cafe::17]:4711";proto=https, for="10.0.0.3:80";by=10.0.0.1`},
+				"X-Forwarded-For": {"9.9.9.9"},
+			},
+			want: "2001:db8:cafe::17",
+		},
+		{
+			name:       "forwarded element without for",
+			header:     utils.ForwardedHeaderForwarded,
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"Forwarded": {"for=1.1.1.1, proto=http"}},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "client xff is ignored with forwarded",
+			header:     utils.ForwardedHeaderForwarded,
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4"}},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "x-real-ip from trusted peer",
+			header:     utils.ForwardedHeaderRealIP,
+			remoteAddr: "[fd00::5]:443",
+			headers:    map[string][]string{"X-Real-IP": {"198.51.100.4"}, "X-Forwarded-For": {"1.2.3.4"}},
+			want:       "198.51.100.4",
+		},
+		{
+			name:       "ipv6 remote addr",
+			header:     utils.ForwardedHeaderXFF,
+			remoteAddr: "[2001:db8::1]:1234",
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "ipv4-mapped remote addr",
+			header:     utils.ForwardedHeaderXFF,
+			remoteAddr: "[::ffff:10.0.0.2]:1234",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.4:5555"}},
+			want:       "198.51.100.4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, values := range tt.headers {
+				for _, v := range values {
+					req.Header.Add(name, v)
+				}
+			}
+			assert.Equal(t, tt.want, resolvers[tt.header].Resolve(req))
+		})
+	}
+
+	// Без доверенных прокси заголовки не учитываются
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "[::1]:1234"
+	req.Header.Set("X-Forwarded-For", "1.1.1.1")
+	assert.Equal(t, "::1", (&utils.ClientIPResolver{}).Resolve(req))
+	assert.Equal(t, "::1", utils.ClientIP(req))
+
+	_, err := utils.NewClientIPResolver(trusted, "x-client-ip")
+	assert.Error(t, err)
+}
+
+// TestClientIPMiddleware — проверяет, что адрес клиента из middleware используется rate limiter
+func TestClientIPMiddleware(t *testing.T) {
+	resolver, err := utils.NewClientIPResolver([]string{"10.0.0.1"}, utils.ForwardedHeaderXFF)
+	assert.NoError(t, err)
+
+	h := newPolicyHandler(t, []config.RateLimitPolicy{
+		{Name: "ip", Key: config.PolicyKeyIP, Rate: 1, Period: time.Minute},
+	})
+	m := handler.NewClientIPMiddleware(resolver)
+	h = m.Middleware(h)
+
+	// Запросы через доверенный прокси считаются по адресу клиента
+	xff := map[string]string{"X-Forwarded-For": "198.51.100.4"}
+	assert.Equal(t, http.StatusOK, policyRequest(h, http.MethodGet, "/", xff).Code)
+	assert.Equal(t, http.StatusTooManyRequests, policyRequest(h, http.MethodGet, "/", xff).Code)
+	assert.Equal(t, http.StatusOK, policyRequest(h, http.MethodGet, "/", map[string]string{"X-Forwarded-For": "198.51.100.5"}).Code)
+
+	// После замены списка доверенных прокси заголовок игнорируется, и все запросы считаются по адресу прокси
+	m.SetResolver(&utils.ClientIPResolver{})
+	assert.Equal(t, http.StatusOK, policyRequest(h, http.MethodGet, "/", xff).Code)
+	assert.Equal(t, http.StatusTooManyRequests, policyRequest(h, http.MethodGet, "/", map[string]string{"X-Forwarded-For": "198.51.100.6"}).Code)
+}
+
+// TestTrustedProxiesValidation — проверяет ошибки в списке доверенных прокси
+func TestTrustedProxiesValidation(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  trusted_proxies: ["10.0.0.0/8", "::1", "10.0.0.0/33", "proxy.local"]
+  forwarded_header: x-client-ip
+balancer:
+  backends: ["http://a:1"]
+`)
+
+	_, err := config.LoadConfig(path)
+	var verr *config.ValidationError
+	assert.True(t, errors.As(err, &verr))
+
+	var paths []string
+	for _, p := range verr.Problems {
+		paths = append(paths, p.Path)
+	}
+	assert.ElementsMatch(t, []string{"server.trusted_proxies[2]", "server.trusted_proxies[3]", "server.forwarded_header"}, paths)
+}
@@ -1,6 +1,8 @@
 package tests
 
 import (
+	"CloudCamp/internal/handler"
+	"CloudCamp/internal/limiter"
 	"bytes"
 	"encoding/json"
 	"github.com/stretchr/testify/assert"
@@ -28,7 +30,10 @@ func TestClientEndpoints(t *testing.T) {
 		}
 		body, _ := json.Marshal(clientData)
 
-		resp, err := http.Post("http://localhost:8080/clients", "application/json", bytes.NewBuffer(body))
+		req, _ := http.NewRequest(http.MethodPost, "http://"+testAdminAddr+"/clients", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+		resp, err := http.DefaultClient.Do(req)
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusCreated, resp.StatusCode)
 		resp.Body.Close()
@@ -43,7 +48,8 @@ func TestClientEndpoints(t *testing.T) {
 	// Тест удаления клиента
 	t.Run("Delete Client", func(t *testing.T) {
 		client := &http.Client{}
-		req, _ := http.NewRequest(http.MethodDelete, "http://localhost:8080/clients?client_id=test_client", nil)
+		req, _ := http.NewRequest(http.MethodDelete, "http://"+testAdminAddr+"/clients?client_id=test_client", nil)
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
 		resp, err := client.Do(req)
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
@@ -55,3 +61,91 @@ func TestClientEndpoints(t *testing.T) {
 		assert.Equal(t, 0, rate)
 	})
 }
+
+// TestClientCRUD — проверяет просмотр, обновление, постраничный вывод и импорт клиентов
+func TestClientCRUD(t *testing.T) {
+	rl := limiter.NewMemoryRateLimiter()
+	h := handler.NewClientHandler(rl, nil)
+
+	var created handler.ClientResponse
+	code := doAdmin(t, h, http.MethodPost, "/clients", `{"client_id":"c1","rate":3,"period":"1m"}`, &created)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, handler.ClientResponse{ClientID: "c1", Rate: 3, Period: "1m0s", Algorithm: "token-bucket", Tokens: 3, LastRefill: created.LastRefill}, created)
+
+	t.Run("Create existing or reserved client is rejected", func(t *testing.T) {
+		assert.Equal(t, http.StatusConflict, doAdmin(t, h, http.MethodPost, "/clients", `{"client_id":"c1","rate":1,"period":"1s"}`, nil))
+		assert.Equal(t, http.StatusBadRequest, doAdmin(t, h, http.MethodPost, "/clients", `{"client_id":"global","rate":1,"period":"1s"}`, nil))
+	})
+
+	t.Run("Get returns current tokens", func(t *testing.T) {
+		assert.True(t, rl.Allow("c1").Allowed)
+
+		var got handler.ClientResponse
+		assert.Equal(t, http.StatusOK, doAdmin(t, h, http.MethodGet, "/clients/c1", "", &got))
+		assert.Equal(t, 2, got.Tokens)
+		assert.False(t, got.LastRefill.IsZero())
+
+		assert.Equal(t, http.StatusNotFound, doAdmin(t, h, http.MethodGet, "/clients/missing", "", nil))
+	})
+
+	t.Run("Patch changes only given fields and keeps tokens", func(t *testing.T) {
+		var got handler.ClientResponse
+		assert.Equal(t, http.StatusOK, doAdmin(t, h, http.MethodPatch, "/clients/c1", `{"rate":10}`, &got))
+		assert.Equal(t, 10, got.Rate)
+		assert.Equal(t, "1m0s", got.Period)
+		// Изменение лимита не восполняет израсходованные токены
+		assert.Equal(t, 2, got.Tokens)
+
+		assert.Equal(t, http.StatusBadRequest, doAdmin(t, h, http.MethodPatch, "/clients/c1", `{"period":"soon"}`, nil))
+		assert.Equal(t, http.StatusNotFound, doAdmin(t, h, http.MethodPatch, "/clients/missing", `{"rate":1}`, nil))
+	})
+
+	t.Run("Put replaces or creates", func(t *testing.T) {
+		var got handler.ClientResponse
+		assert.Equal(t, http.StatusOK, doAdmin(t, h, http.MethodPut, "/clients/c1", `{"rate":5,"period":"10s"}`, &got))
+		assert.Equal(t, "10s", got.Period)
+		assert.Equal(t, http.StatusCreated, doAdmin(t, h, http.MethodPut, "/clients/c2", `{"rate":5,"period":"10s"}`, nil))
+		assert.Equal(t, http.StatusBadRequest, doAdmin(t, h, http.MethodPut, "/clients/c2", `{"client_id":"other","rate":5,"period":"10s"}`, nil))
+	})
+
+	t.Run("Import is atomic", func(t *testing.T) {
+		var failed handler.ClientImportErrorResponse
+		code := doAdmin(t, h, http.MethodPost, "/clients/import",
+			`[{"client_id":"c3","rate":1,"period":"1s"},{"client_id":"c4","rate":0,"period":"1s"},{"client_id":"c3","rate":1,"period":"1s"}]`, &failed)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Len(t, failed.Errors, 2)
+		assert.Equal(t, 1, failed.Errors[0].Index)
+		assert.Equal(t, 2, failed.Errors[1].Index)
+		_, exists := rl.Client("c3")
+		assert.False(t, exists)
+
+		var imported handler.ClientImportResponse
+		code = doAdmin(t, h, http.MethodPost, "/clients/import",
+			`[{"client_id":"c2","rate":7,"period":"1m"},{"client_id":"c3","rate":1,"period":"1s"},{"client_id":"c4","rate":2,"period":"1s"}]`, &imported)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, handler.ClientImportResponse{Created: 2, Updated: 1}, imported)
+	})
+
+	t.Run("List is paginated and sorted", func(t *testing.T) {
+		var page handler.ClientListResponse
+		assert.Equal(t, http.StatusOK, doAdmin(t, h, http.MethodGet, "/clients?offset=1&limit=2", "", &page))
+		assert.Equal(t, 4, page.Total)
+		assert.Len(t, page.Clients, 2)
+		assert.Equal(t, "c2", page.Clients[0].ClientID)
+		assert.Equal(t, "c3", page.Clients[1].ClientID)
+
+		assert.Equal(t, http.StatusOK, doAdmin(t, h, http.MethodGet, "/clients?offset=10", "", &page))
+		assert.Empty(t, page.Clients)
+		assert.Equal(t, http.StatusOK, doAdmin(t, h, http.MethodGet, "/clients?offset=9223372036854775800&limit=1000", "", &page))
+		assert.Empty(t, page.Clients)
+
+		assert.Equal(t, http.StatusBadRequest, doAdmin(t, h, http.MethodGet, "/clients?limit=0", "", nil))
+	})
+
+	t.Run("Delete returns removed client", func(t *testing.T) {
+		var removed handler.ClientResponse
+		assert.Equal(t, http.StatusOK, doAdmin(t, h, http.MethodDelete, "/clients/c4", "", &removed))
+		assert.Equal(t, "c4", removed.ClientID)
+		assert.Equal(t, http.StatusNotFound, doAdmin(t, h, http.MethodDelete, "/clients/c4", "", nil))
+	})
+}
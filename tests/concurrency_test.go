@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"CloudCamp/internal/balancer"
+	"CloudCamp/internal/config"
+	"CloudCamp/internal/domain/balancerDomain"
+	"CloudCamp/internal/handler"
+	"CloudCamp/internal/limiter"
+	"CloudCamp/pkg/utils"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConcurrencyLimiter — проверяет лимит одновременных запросов клиента и очередь ожидания
+func TestConcurrencyLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Reject without queue", func(t *testing.T) {
+		l := limiter.NewConcurrencyLimiter(config.ConcurrencyConfig{
+			Enabled:   true,
+			PerClient: 2,
+			Clients:   map[string]int{"vip": 0},
+		})
+
+		release1, ok := l.Acquire(ctx, "client1")
+		assert.True(t, ok)
+		_, ok = l.Acquire(ctx, "client1")
+		assert.True(t, ok)
+		_, ok = l.Acquire(ctx, "client1")
+		assert.False(t, ok)
+		assert.Equal(t, 2, l.InFlight("client1"))
+
+		// Другие клиенты считаются отдельно, а для vip лимита нет
+		_, ok = l.Acquire(ctx, "client2")
+		assert.True(t, ok)
+		for i := 0; i < 5; i++ {
+			_, ok = l.Acquire(ctx, "vip")
+			assert.True(t, ok)
+		}
+
+		// Повторное освобождение не освобождает чужое место
+		release1()
+		release1()
+		assert.Equal(t, 1, l.InFlight("client1"))
+		_, ok = l.Acquire(ctx, "client1")
+		assert.True(t, ok)
+	})
+
+	t.Run("Queue", func(t *testing.T) {
+		l := limiter.NewConcurrencyLimiter(config.ConcurrencyConfig{
+			Enabled:      true,
+			PerClient:    1,
+			QueueTimeout: time.Second,
+		})
+
+		release, ok := l.Acquire(ctx, "client1")
+		assert.True(t, ok)
+
+		// Ожидающие запросы получают место в порядке очереди
+		var (
+			mu    sync.Mutex
+			order []int
+			wg    sync.WaitGroup
+		)
+		for i := 1; i <= 2; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				next, ok := l.Acquire(ctx, "client1")
+				assert.True(t, ok)
+				mu.Lock()
+				order = append(order, i)
+				mu.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				next()
+			}(i)
+			assert.Eventually(t, func() bool { return l.Queued("client1") == i }, time.Second, time.Millisecond)
+		}
+
+		release()
+		wg.Wait()
+		assert.Equal(t, []int{1, 2}, order)
+		assert.Equal(t, 0, l.InFlight("client1"))
+	})
+
+	t.Run("Queue timeout and cancellation", func(t *testing.T) {
+		l := limiter.NewConcurrencyLimiter(config.ConcurrencyConfig{
+			Enabled:      true,
+			PerClient:    1,
+			QueueTimeout: 50 * time.Millisecond,
+		})
+		release, _ := l.Acquire(ctx, "client1")
+		defer release()
+
+		start := time.Now()
+		_, ok := l.Acquire(ctx, "client1")
+		assert.False(t, ok)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, ok = l.Acquire(canceled, "client1")
+		assert.False(t, ok)
+		assert.Equal(t, 0, l.Queued("client1"))
+		assert.Equal(t, 1, l.InFlight("client1"))
+	})
+
+	t.Run("Raised limit wakes waiters", func(t *testing.T) {
+		cfg := config.ConcurrencyConfig{Enabled: true, PerClient: 1, QueueTimeout: time.Second}
+		l := limiter.NewConcurrencyLimiter(cfg)
+		_, _ = l.Acquire(ctx, "client1")
+
+		done := make(chan bool)
+		go func() {
+			_, ok := l.Acquire(ctx, "client1")
+			done <- ok
+		}()
+		assert.Eventually(t, func() bool { return l.Queued("client1") == 1 }, time.Second, time.Millisecond)
+
+		cfg.PerClient = 2
+		l.UpdateConfig(cfg)
+		assert.True(t, <-done)
+	})
+}
+
+// TestConcurrencyMiddleware — проверяет отклонение лишних одновременных запросов клиента с кодом 429
+func TestConcurrencyMiddleware(t *testing.T) {
+	l := limiter.NewConcurrencyLimiter(config.ConcurrencyConfig{Enabled: true, PerClient: 1})
+	started, finish := make(chan struct{}), make(chan struct{})
+	h := handler.NewConcurrencyMiddleware(l).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-finish
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(clientID, authenticated string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Client-ID", clientID)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, utils.WithAuthenticatedClient(req, authenticated))
+		return rec
+	}
+
+	first := make(chan int)
+	go func() { first <- send("slow", "").Code }()
+	<-started
+
+	// Новое значение X-Client-ID не обходит лимит: клиент определяется по IP
+	assert.Equal(t, http.StatusTooManyRequests, send("slow", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("fresh", "").Code)
+
+	// Аутентифицированный клиент с того же адреса считается отдельно
+	second := make(chan int)
+	go func() { second <- send("", "tenant").Code }()
+	<-started
+
+	close(finish)
+	assert.Equal(t, http.StatusOK, <-first)
+	assert.Equal(t, http.StatusOK, <-second)
+	go func() { <-started }()
+	assert.Equal(t, http.StatusOK, send("slow", "").Code)
+}
+
+// TestBackendConcurrencyLimit — проверяет лимит одновременных запросов к бэкенду
+func TestBackendConcurrencyLimit(t *testing.T) {
+	started, finish := make(chan struct{}, 10), make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-finish
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(slow.Close)
+
+	backend := balancerDomain.NewBackend(slow.URL, 1)
+	backend.SetMaxConnections(1)
+	proxy := handler.NewProxyHandler(balancer.NewLeastConnectionsBalancer([]*balancerDomain.Backend{backend}), config.ProxyConfig{})
+
+	send := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec
+	}
+
+	first := make(chan int)
+	go func() { first <- send().Code }()
+	<-started
+
+	// Без очереди запрос к занятому бэкенду сразу отклоняется
+	rec := send()
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "All backends are busy")
+
+	// С очередью запрос дожидается освобождения места
+	proxy.SetAdmissionQueue(limiter.NewAdmissionQueue(config.ConcurrencyConfig{Enabled: true, QueueTimeout: time.Second}))
+	second := make(chan int)
+	go func() { second <- send().Code }()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int64(1), backend.GetActiveConnections())
+
+	close(finish)
+	assert.Equal(t, http.StatusOK, <-first)
+	assert.Equal(t, http.StatusOK, <-second)
+	assert.Equal(t, int64(0), backend.GetActiveConnections())
+}
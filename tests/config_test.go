@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"CloudCamp/internal/config"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeConfig записывает временный файл конфигурации
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+// TestLoadConfigExample — проверяет, что конфигурация из репозитория проходит валидацию
+func TestLoadConfigExample(t *testing.T) {
+	cfg, err := config.LoadConfig("../configs/config.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://backend1:8081", cfg.Balancer.Backends[0].URL)
+	assert.Equal(t, 3, cfg.Balancer.Backends[0].Weight)
+	assert.Equal(t, "http://backend2:8082", cfg.Balancer.Backends[1].URL)
+}
+
+// TestConfigValidation — проверяет, что все проблемы сообщаются сразу с путями к полям
+func TestConfigValidation(t *testing.T) {
+	path := writeConfig(t, `
+env: development
+server:
+  port: 0
+admin:
+  addr: "0.0.0.0:9090"
+balancer:
+  stratgy: random
+  backends:
+    - "http://a:1"
+    - url: "b:2"
+      weight: heavy
+    - "http://a:1"
+health_checker:
+  interval: 0s
+  path: /health
+rate_limiter:
+  enabled: true
+  interval: 10s
+  rate: 10
+  period: 1m
+  clients:
+    client1:
+      rate: 0
+      period: 1m
+      algorithm: leaky-bucket
+log:
+  file_path: ./logs/app.log
+  dir: ./logs
+`)
+
+	_, err := config.LoadConfig(path)
+	var verr *config.ValidationError
+	assert.True(t, errors.As(err, &verr))
+
+	var paths []string
+	for _, p := range verr.Problems {
+		paths = append(paths, p.Path)
+	}
+	assert.ElementsMatch(t, []string{
+		"server.port",
+		"admin.token",
+		"balancer.stratgy",
+		"balancer.backends[1]",
+		"balancer.backends[1].weight",
+		"balancer.backends[2]",
+		"health_checker.interval",
+		"rate_limiter.clients.client1.rate",
+		"rate_limiter.clients.client1.algorithm",
+	}, paths)
+}
+
+// TestConfigOverrides — проверяет приоритет источников: флаги > env > файл > значения по умолчанию
+func TestConfigOverrides(t *testing.T) {
+	path := writeConfig(t, `
+env: production
+server:
+  port: 8080
+balancer:
+  backends: ["http://file:1"]
+  strategy: random
+`)
+
+	t.Setenv("CLOUDCAMP_SERVER_PORT", "9000")
+	t.Setenv("CLOUDCAMP_BALANCER_BACKENDS", "http://a:1, http://b:2,http://c:3")
+	t.Setenv("CLOUDCAMP_RATE_LIMITER_CLIENTS", "{client1: {rate: 7, period: 1m}}")
+
+	cfg, err := config.Load(path, config.Overrides{
+		"server.port":             "9100",
+		"health_checker.interval": "3s",
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, config.EnvProd, cfg.Env)                   // файл
+	assert.Equal(t, "random", cfg.Balancer.Strategy)           // файл
+	assert.Equal(t, 9100, cfg.Server.Port)                     // флаг важнее env
+	assert.Equal(t, 3*time.Second, cfg.HealthChecker.Interval) // флаг
+	assert.Equal(t, "/health", cfg.HealthChecker.Path)         // значение по умолчанию
+	assert.Equal(t, 7, cfg.RateLimiter.Clients["client1"].RateLimit)
+	assert.Len(t, cfg.Balancer.Backends, 3)
+	assert.Equal(t, "http://b:2", cfg.Balancer.Backends[1].URL)
+
+	// Некорректные значения из env сообщаются с именем переменной
+	t.Setenv("CLOUDCAMP_SERVER_PORT", "abc")
+	_, err = config.Load(path, nil)
+	assert.ErrorContains(t, err, "CLOUDCAMP_SERVER_PORT")
+
+	// Конфигурация может собираться только из env и флагов
+	t.Setenv("CLOUDCAMP_SERVER_PORT", "8081")
+	cfg, err = config.Load("", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 8081, cfg.Server.Port)
+}
@@ -10,6 +10,12 @@ import (
 	"time"
 )
 
+// Адрес и токен admin listener тестового сервера
+const (
+	testAdminAddr  = "127.0.0.1:9090"
+	testAdminToken = "test-token"
+)
+
 type MockBackend struct{}
 
 func (m *MockBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -27,9 +33,10 @@ func setupTestServerWithMocksForTest(t *testing.T) *app.Server {
 	// Конфиг для сервера с балансировщиком и rate limiting
 	cfg := &config.Config{
 		Server: config.ServerConfig{Port: 8080},
+		Admin:  config.AdminConfig{Addr: testAdminAddr, Token: testAdminToken},
 		Balancer: config.BalancerConfig{
 			Strategy: "round-robin",
-			Backends: []string{"http://localhost:8081", "http://localhost:8082"},
+			Backends: []config.BackendConfig{{URL: "http://localhost:8081"}, {URL: "http://localhost:8082"}},
 		},
 		RateLimiter: config.RateLimitConfig{
 			Enabled: true,
@@ -57,9 +64,10 @@ func setupTestServerWithMocksForBenchmark(b *testing.B) *app.Server {
 	// Конфиг для сервера с балансировщиком и rate limiting
 	cfg := &config.Config{
 		Server: config.ServerConfig{Port: 8080},
+		Admin:  config.AdminConfig{Addr: testAdminAddr, Token: testAdminToken},
 		Balancer: config.BalancerConfig{
 			Strategy: "round-robin",
-			Backends: []string{"http://localhost:8081", "http://localhost:8082"},
+			Backends: []config.BackendConfig{{URL: "http://localhost:8081"}, {URL: "http://localhost:8082"}},
 		},
 		RateLimiter: config.RateLimitConfig{
 			Enabled: true,
@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"CloudCamp/internal/config"
+	"CloudCamp/internal/handler"
+	"CloudCamp/internal/limiter"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var algorithms = []string{
+	config.AlgorithmTokenBucket,
+	config.AlgorithmSlidingWindowLog,
+	config.AlgorithmSlidingWindowCounter,
+	config.AlgorithmGCRA,
+}
+
+// TestRateLimitAlgorithms — проверяет всплеск до лимита и восстановление при частых запросах
+func TestRateLimitAlgorithms(t *testing.T) {
+	for _, algorithm := range algorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			l := limiter.NewMemoryRateLimiter()
+			assert.NoError(t, l.SetAlgorithm("client1", algorithm))
+			assert.NoError(t, l.SetClientLimit("client1", 4, 400*time.Millisecond))
+
+			allowed := 0
+			for i := 0; i < 10; i++ {
+				if l.Allow("client1").Allowed {
+					allowed++
+				}
+			}
+			assert.Equal(t, 4, allowed)
+
+			// Частые отклоненные запросы не должны мешать восстановлению лимита
+			start := time.Now()
+			for !l.Allow("client1").Allowed {
+				if time.Since(start) > time.Second {
+					t.Fatal("limit was not restored under frequent requests")
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+			state, ok := l.Client("client1")
+			assert.True(t, ok)
+			assert.Equal(t, algorithm, state.Algorithm)
+		})
+	}
+}
+
+// TestTokenBucketFractionalTokens — проверяет, что пополнение не округляется до целых токенов
+func TestTokenBucketFractionalTokens(t *testing.T) {
+	l := limiter.NewMemoryRateLimiter()
+	assert.NoError(t, l.SetClientLimit("client1", 2, 200*time.Millisecond))
+	assert.True(t, l.Allow("client1").Allowed)
+	assert.True(t, l.Allow("client1").Allowed)
+
+	// За 50 мс накапливается половина токена
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, l.Allow("client1").Allowed)
+	state, _ := l.Client("client1")
+	assert.InDelta(t, 0.5, state.Tokens, 0.3)
+
+	time.Sleep(70 * time.Millisecond)
+	assert.True(t, l.Allow("client1").Allowed)
+}
+
+// TestRateLimitAlgorithmSelection — проверяет алгоритм по умолчанию и алгоритм клиента
+func TestRateLimitAlgorithmSelection(t *testing.T) {
+	l := limiter.NewMemoryRateLimiter()
+	assert.Error(t, l.SetAlgorithm("global", "leaky-bucket"))
+
+	assert.NoError(t, l.SetAlgorithm("global", config.AlgorithmGCRA))
+	assert.NoError(t, l.SetAlgorithm("client2", config.AlgorithmSlidingWindowLog))
+	assert.NoError(t, l.SetClientLimit("client1", 2, time.Minute))
+	assert.NoError(t, l.SetClientLimit("client2", 2, time.Minute))
+
+	state, _ := l.Client("client1")
+	assert.Equal(t, config.AlgorithmGCRA, state.Algorithm)
+	state, _ = l.Client("client2")
+	assert.Equal(t, config.AlgorithmSlidingWindowLog, state.Algorithm)
+
+	// Смена алгоритма не восстанавливает исчерпанный лимит
+	assert.True(t, l.Allow("client2").Allowed)
+	assert.True(t, l.Allow("client2").Allowed)
+	assert.NoError(t, l.SetAlgorithm("client2", ""))
+	state, _ = l.Client("client2")
+	assert.Equal(t, config.AlgorithmGCRA, state.Algorithm)
+	assert.False(t, l.Allow("client2").Allowed)
+
+	// Смена алгоритма по умолчанию переводит клиентов без собственного алгоритма
+	assert.NoError(t, l.SetAlgorithm("global", config.AlgorithmSlidingWindowCounter))
+	state, _ = l.Client("client1")
+	assert.Equal(t, config.AlgorithmSlidingWindowCounter, state.Algorithm)
+	assert.Equal(t, 2.0, state.Tokens)
+}
+
+// TestRateLimitDecision — проверяет описание решения: лимит, остаток и время восстановления
+func TestRateLimitDecision(t *testing.T) {
+	for _, algorithm := range algorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			l := limiter.NewMemoryRateLimiter()
+			assert.NoError(t, l.SetAlgorithm("client1", algorithm))
+			assert.NoError(t, l.SetClientLimit("client1", 4, 4*time.Second))
+
+			d := l.Allow("client1")
+			assert.True(t, d.Allowed)
+			assert.Equal(t, 4, d.Limit)
+			assert.Equal(t, 3, d.Remaining)
+			assert.Zero(t, d.RetryAfter)
+			assert.Greater(t, d.Reset, time.Duration(0))
+
+			for i := 0; i < 3; i++ {
+				l.Allow("client1")
+			}
+			d = l.Allow("client1")
+			assert.False(t, d.Allowed)
+			assert.Equal(t, 0, d.Remaining)
+			assert.Greater(t, d.RetryAfter, time.Duration(0))
+			assert.LessOrEqual(t, d.RetryAfter, d.Reset)
+			assert.LessOrEqual(t, d.Reset, 8*time.Second)
+		})
+	}
+
+	t.Run("token bucket timings", func(t *testing.T) {
+		l := limiter.NewMemoryRateLimiter()
+		assert.NoError(t, l.SetClientLimit("client1", 2, 2*time.Second))
+		l.Allow("client1")
+		l.Allow("client1")
+
+		d := l.Allow("client1")
+		assert.InDelta(t, time.Second, d.RetryAfter, float64(50*time.Millisecond))
+		assert.InDelta(t, 2*time.Second, d.Reset, float64(50*time.Millisecond))
+	})
+
+	t.Run("strictest limit is reported", func(t *testing.T) {
+		l := limiter.NewMemoryRateLimiter()
+		assert.NoError(t, l.SetLimit("global", 10, time.Minute))
+		assert.NoError(t, l.SetClientLimit("client1", 2, time.Minute))
+
+		d := l.Allow("client1")
+		assert.Equal(t, 2, d.Limit)
+		assert.Equal(t, 1, d.Remaining)
+
+		d = l.Allow("other")
+		assert.Equal(t, 10, d.Limit)
+		assert.Equal(t, 8, d.Remaining)
+
+		l.Allow("client1")
+		d = l.Allow("client1")
+		assert.False(t, d.Allowed)
+		assert.Equal(t, 2, d.Limit)
+	})
+}
+
+// TestRateLimitHeaders — проверяет заголовки RateLimit-* и Retry-After
+func TestRateLimitHeaders(t *testing.T) {
+	l := limiter.NewMemoryRateLimiter()
+	assert.NoError(t, l.SetClientLimit("client1", 1, 10*time.Second))
+	h := handler.NewRateLimiterMiddleware(l).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(clientID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Client-ID", clientID)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send("client1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "10", rec.Header().Get("RateLimit-Reset"))
+	assert.Empty(t, rec.Header().Get("Retry-After"))
+
+	rec = send("client1")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "10", rec.Header().Get("Retry-After"))
+
+	// Без применимых лимитов заголовки не добавляются
+	rec = send("unlimited")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+}
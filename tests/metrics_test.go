@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"CloudCamp/internal/balancer"
+	"CloudCamp/internal/config"
+	"CloudCamp/internal/domain/balancerDomain"
+	"CloudCamp/internal/handler"
+	"CloudCamp/internal/limiter"
+	"CloudCamp/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// scrapeMetrics возвращает текущий вывод /metrics
+func scrapeMetrics(t *testing.T) string {
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "version=0.0.4")
+
+	body, err := io.ReadAll(rec.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
+
+// TestMetricsExposition — проверяет текстовый формат счетчиков, гистограмм и gauge
+func TestMetricsExposition(t *testing.T) {
+	counter := metrics.NewCounterVec("test_exposition_total", "Test counter.", "path")
+	counter.Inc(`/a"b`)
+	counter.Add(2, `/a"b`)
+
+	histogram := metrics.NewHistogramVec("test_exposition_seconds", "Test histogram.", []float64{0.1, 1}, "op")
+	histogram.Observe(0.05, "read")
+	histogram.Observe(0.5, "read")
+	histogram.Observe(5, "read")
+
+	metrics.Default.Register(metrics.NewGaugeFunc("test_exposition_gauge", "Test gauge.", []string{"name"}, func() []metrics.Sample {
+		return []metrics.Sample{{Labels: []string{"b"}, Value: 2}, {Labels: []string{"a"}, Value: 1}}
+	}))
+
+	out := scrapeMetrics(t)
+
+	assert.Contains(t, out, "# HELP test_exposition_total Test counter.\n# TYPE test_exposition_total counter\n")
+	assert.Contains(t, out, `test_exposition_total{path="/a\"b"} 3`+"\n")
+
+	assert.Contains(t, out, "# TYPE test_exposition_seconds histogram\n")
+	assert.Contains(t, out, `test_exposition_seconds_bucket{op="read",le="0.1"} 1`+"\n")
+	assert.Contains(t, out, `test_exposition_seconds_bucket{op="read",le="1"} 2`+"\n")
+	assert.Contains(t, out, `test_exposition_seconds_bucket{op="read",le="+Inf"} 3`+"\n")
+	assert.Contains(t, out, `test_exposition_seconds_sum{op="read"} 5.55`+"\n")
+	assert.Contains(t, out, `test_exposition_seconds_count{op="read"} 3`+"\n")
+
+	assert.Contains(t, out, "test_exposition_gauge{name=\"a\"} 1\ntest_exposition_gauge{name=\"b\"} 2\n")
+}
+
+// TestMetricsInstrumentation — проверяет метрики прокси, бэкендов и rate limiter
+func TestMetricsInstrumentation(t *testing.T) {
+	backendSrv := newTestBackend(t, http.StatusOK, "ok", nil)
+	backend := balancerDomain.NewBackend(backendSrv.URL, 1)
+	backend.ID = "metrics-backend"
+	rr := balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{backend})
+	metrics.RegisterBackends(rr)
+
+	proxy := handler.NewProxyHandler(rr, config.ProxyConfig{})
+	before := metrics.ProxyRequests.Value("metrics-backend", "2xx", http.MethodGet)
+	for i := 0; i < 3; i++ {
+		proxy.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	assert.Equal(t, before+3, metrics.ProxyRequests.Value("metrics-backend", "2xx", http.MethodGet))
+
+	rl := limiter.NewMemoryRateLimiter()
+	assert.NoError(t, rl.SetClientLimit("metrics-client", 2, time.Minute))
+	metrics.RegisterBuckets(rl)
+
+	middleware := handler.NewRateLimiterMiddleware(rl).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Client-ID", "metrics-client")
+		middleware.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	// Клиенты без индивидуального лимита учитываются под общей меткой
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Client-ID", "random-key")
+	middleware.ServeHTTP(httptest.NewRecorder(), req)
+
+	out := scrapeMetrics(t)
+
+	assert.Contains(t, out, `cloudcamp_proxy_request_duration_seconds_count{backend="metrics-backend",method="GET"}`)
+	assert.Contains(t, out, `cloudcamp_backend_active_connections{backend="metrics-backend"} 0`+"\n")
+	assert.Contains(t, out, `cloudcamp_backend_up{backend="metrics-backend"} 1`+"\n")
+	assert.Contains(t, out, `cloudcamp_ratelimit_decisions_total{client="metrics-client",decision="allow"} 2`+"\n")
+	assert.Contains(t, out, `cloudcamp_ratelimit_decisions_total{client="metrics-client",decision="deny"} 1`+"\n")
+	assert.Contains(t, out, `cloudcamp_ratelimit_decisions_total{client="default",decision="allow"}`)
+	assert.NotContains(t, out, "random-key")
+	// Токены дробные: к моменту снимка бакет успевает пополниться на долю токена
+	assert.Regexp(t, `cloudcamp_ratelimit_bucket_tokens\{client="metrics-client"\} (0|0\.\d+|\d(\.\d+)?e-\d+)\n`, out)
+	assert.Contains(t, out, `cloudcamp_ratelimit_bucket_capacity{client="metrics-client"} 2`+"\n")
+	assert.Contains(t, out, "# TYPE cloudcamp_health_checks_total counter")
+}
@@ -0,0 +1,238 @@
+package tests
+
+import (
+	"CloudCamp/internal/config"
+	"CloudCamp/internal/handler"
+	"CloudCamp/internal/limiter"
+	"CloudCamp/pkg/utils"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testJWTSecret = "jwt-secret"
+
+// newTestJWT подписывает токен HS256 с указанными полями
+func newTestJWT(t *testing.T, alg, secret string, claims map[string]any) string {
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	assert.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	assert.NoError(t, err)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// newPolicyHandler создает middleware лимитов с политиками поверх обработчика, отвечающего 200
+func newPolicyHandler(t *testing.T, policies []config.RateLimitPolicy) http.Handler {
+	cfg := config.Default().RateLimiter
+	cfg.Enabled = true
+	cfg.Auth.APIKeys = map[string]string{"partner": "partner-key"}
+	cfg.Auth.JWT.Secret = testJWTSecret
+	cfg.Policies = policies
+
+	set, err := limiter.NewPolicySet(cfg)
+	assert.NoError(t, err)
+
+	m := handler.NewRateLimiterMiddleware(limiter.NewMemoryRateLimiter())
+	m.SetPolicies(set)
+	return m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+// policyRequest выполняет запрос и возвращает ответ
+func policyRequest(h http.Handler, method, target string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestRateLimitPolicyMatch — проверяет условия политик и ключ счетчика
+func TestRateLimitPolicyMatch(t *testing.T) {
+	h := newPolicyHandler(t, []config.RateLimitPolicy{
+		{
+			Name:   "writes",
+			Match:  config.PolicyMatch{PathPrefix: "/api/", Methods: []string{"post"}, Hosts: []string{"API.example.com"}},
+			Key:    config.PolicyKeyIP,
+			Rate:   2,
+			Period: time.Minute,
+		},
+	})
+
+	// X-Client-ID не помогает обойти политику, которая считает по IP
+	for i, clientID := range []string{"a", "b"} {
+		rec := policyRequest(h, http.MethodPost, "http://api.example.com:8080/api/orders", map[string]string{"X-Client-ID": clientID})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, []string{"1", "0"}[i], rec.Header().Get("RateLimit-Remaining"))
+	}
+	rec := policyRequest(h, http.MethodPost, "http://api.example.com/api/orders", map[string]string{"X-Client-ID": "c"})
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+	// Запросы, не подходящие по методу, пути или хосту, политика не учитывает
+	assert.Equal(t, http.StatusOK, policyRequest(h, http.MethodGet, "http://api.example.com/api/orders", nil).Code)
+	assert.Equal(t, http.StatusOK, policyRequest(h, http.MethodPost, "http://api.example.com/health", nil).Code)
+	assert.Equal(t, http.StatusOK, policyRequest(h, http.MethodPost, "http://other.example.com/api/orders", nil).Code)
+}
+
+// TestRateLimitPolicyAuth — проверяет политики по API-ключу и subject JWT
+func TestRateLimitPolicyAuth(t *testing.T) {
+	h := newPolicyHandler(t, []config.RateLimitPolicy{
+		{Name: "partners", Match: config.PolicyMatch{APIKeys: []string{"*"}}, Key: config.PolicyKeyAPIKey, Rate: 2, Period: time.Minute},
+		{Name: "users", Match: config.PolicyMatch{JWTSubjects: []string{"alice"}}, Key: config.PolicyKeyJWTSubject, Rate: 1, Period: time.Minute},
+	})
+
+	t.Run("API key", func(t *testing.T) {
+		key := map[string]string{"X-API-Key": "partner-key"}
+		assert.Equal(t, http.StatusOK, policyRequest(h, http.MethodGet, "/", key).Code)
+		assert.Equal(t, http.StatusOK, policyRequest(h, http.MethodGet, "/", key).Code)
+		assert.Equal(t, http.StatusTooManyRequests, policyRequest(h, http.MethodGet, "/", key).Code)
+
+		// Неизвестный ключ не аутентифицирует клиента, и политика к нему не применяется
+		rec := policyRequest(h, http.MethodGet, "/", map[string]string{"X-API-Key": "guess"})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+	})
+
+	t.Run("JWT subject", func(t *testing.T) {
+		exp := float64(time.Now().Add(time.Hour).Unix())
+		bearer := func(token string) map[string]string {
+			return map[string]string{"Authorization": "Bearer " + token}
+		}
+
+		alice := newTestJWT(t, "HS256", testJWTSecret, map[string]any{"sub": "alice", "exp": exp})
+		assert.Equal(t, http.StatusOK, policyRequest(h, http.MethodGet, "/", bearer(alice)).Code)
+		assert.Equal(t, http.StatusTooManyRequests, policyRequest(h, http.MethodGet, "/", bearer(alice)).Code)
+
+		for name, token := range map[string]string{
+			"other subject":   newTestJWT(t, "HS256", testJWTSecret, map[string]any{"sub": "bob", "exp": exp}),
+			"wrong signature": newTestJWT(t, "HS256", "forged", map[string]any{"sub": "alice", "exp": exp}),
+			"expired":         newTestJWT(t, "HS256", testJWTSecret, map[string]any{"sub": "alice", "exp": float64(time.Now().Add(-time.Minute).Unix())}),
+			"alg none":        newTestJWT(t, "none", testJWTSecret, map[string]any{"sub": "alice", "exp": exp}),
+		} {
+			assert.Equal(t, http.StatusOK, policyRequest(h, http.MethodGet, "/", bearer(token)).Code, name)
+		}
+	})
+}
+
+// TestRateLimitPolicyStrictest — проверяет, что при нескольких политиках решение определяет самая строгая,
+// а отклоненный запрос не расходует лимиты остальных политик
+func TestRateLimitPolicyStrictest(t *testing.T) {
+	h := newPolicyHandler(t, []config.RateLimitPolicy{
+		{Name: "partners", Match: config.PolicyMatch{APIKeys: []string{"partner"}}, Key: config.PolicyKeyAPIKey, Rate: 3, Period: time.Minute},
+		{Name: "tenant", Match: config.PolicyMatch{PathPrefix: "/reports"}, Key: "header:X-Tenant-ID", Rate: 1, Period: time.Minute},
+		{Name: "route", Match: config.PolicyMatch{PathPrefix: "/reports"}, Key: config.PolicyKeyRoute, Rate: 10, Period: time.Minute},
+	})
+	headers := map[string]string{"X-API-Key": "partner-key", "X-Tenant-ID": "acme"}
+
+	rec := policyRequest(h, http.MethodGet, "/reports", headers)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+
+	rec = policyRequest(h, http.MethodGet, "/reports", headers)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Limit"))
+
+	// Отклоненный запрос не списан из лимита партнера: осталось 2 из 3
+	rec = policyRequest(h, http.MethodGet, "/orders", headers)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "3", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+
+	// Другой арендатор считается отдельно
+	headers["X-Tenant-ID"] = "globex"
+	assert.Equal(t, http.StatusOK, policyRequest(h, http.MethodGet, "/reports", headers).Code)
+}
+
+// TestRateLimitPolicyValidation — проверяет ошибки конфигурации политик
+func TestRateLimitPolicyValidation(t *testing.T) {
+	path := writeConfig(t, `
+balancer:
+  backends: ["http://a:1"]
+rate_limiter:
+  enabled: true
+  rate: 10
+  period: 1m
+  policies:
+    - name: writes
+      match:
+        path_prefix: api
+      key: ip
+      rate: 5
+      period: 1m
+    - name: writes
+      key: api-key
+      rate: 0
+      period: 1m
+    - name: users
+      match:
+        jwt_subjects: ["*"]
+      key: "header:"
+      period: 1m
+      rate: 1
+    - name: "a:b"
+      key: cookie
+      rate: 1
+      period: 0s
+`)
+
+	_, err := config.LoadConfig(path)
+	var verr *config.ValidationError
+	assert.True(t, errors.As(err, &verr))
+
+	var paths []string
+	for _, p := range verr.Problems {
+		paths = append(paths, p.Path)
+	}
+	assert.ElementsMatch(t, []string{
+		"rate_limiter.policies[0].match.path_prefix",
+		"rate_limiter.policies[1].name",
+		"rate_limiter.policies[1].key",
+		"rate_limiter.policies[1].rate",
+		"rate_limiter.policies[2].match.jwt_subjects",
+		"rate_limiter.policies[2].key",
+		"rate_limiter.policies[3].name",
+		"rate_limiter.policies[3].key",
+		"rate_limiter.policies[3].period",
+	}, paths)
+}
+
+// TestRateLimiterAuthenticatedClient — проверяет, что клиент, аутентифицированный по API-ключу,
+// передается следующим обработчикам, а X-Client-ID — нет
+func TestRateLimiterAuthenticatedClient(t *testing.T) {
+	cfg := config.Default().RateLimiter
+	cfg.Enabled = true
+	cfg.Auth.APIKeys = map[string]string{"partner": "partner-key"}
+	set, err := limiter.NewPolicySet(cfg)
+	assert.NoError(t, err)
+
+	var got string
+	m := handler.NewRateLimiterMiddleware(limiter.NewMemoryRateLimiter())
+	m.SetPolicies(set)
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = utils.TrustedClientID(r)
+	}))
+
+	policyRequest(h, http.MethodGet, "/", map[string]string{"X-API-Key": "partner-key", "X-Client-ID": "vip"})
+	assert.Equal(t, "partner", got)
+
+	policyRequest(h, http.MethodGet, "/", map[string]string{"X-API-Key": "wrong", "X-Client-ID": "vip"})
+	assert.Equal(t, "10.0.0.1", got)
+}
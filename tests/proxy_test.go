@@ -0,0 +1,344 @@
+package tests
+
+import (
+	"CloudCamp/internal/balancer"
+	"CloudCamp/internal/config"
+	"CloudCamp/internal/domain/balancerDomain"
+	"CloudCamp/internal/handler"
+	"bufio"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestBackend запускает тестовый бэкенд, отвечающий указанным статусом и именем
+func newTestBackend(t *testing.T, status int, name string, hits *atomic.Int64) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits != nil {
+			hits.Add(1)
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, name+":"+string(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestProxyRetry — проверяет повтор запроса на другом бэкенде
+func TestProxyRetry(t *testing.T) {
+	var badHits, goodHits atomic.Int64
+	bad := newTestBackend(t, http.StatusServiceUnavailable, "bad", &badHits)
+	good := newTestBackend(t, http.StatusOK, "good", &goodHits)
+
+	// Недоступный бэкенд: сервер закрыт, соединение не устанавливается
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	rr := balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{
+		balancerDomain.NewBackend(bad.URL, 1),
+		balancerDomain.NewBackend(dead.URL, 1),
+		balancerDomain.NewBackend(good.URL, 1),
+	})
+	proxy := handler.NewProxyHandler(rr, config.ProxyConfig{
+		Retry: config.RetryConfig{
+			MaxAttempts: 3,
+			StatusCodes: []int{http.StatusServiceUnavailable},
+		},
+	})
+
+	t.Run("Idempotent request is retried with body", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/", strings.NewReader("payload")))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "good:payload", rec.Body.String())
+		assert.Equal(t, int64(1), goodHits.Load())
+	})
+
+	t.Run("Non-idempotent request is not retried", func(t *testing.T) {
+		var badHits, goodHits atomic.Int64
+		bad := newTestBackend(t, http.StatusServiceUnavailable, "bad", &badHits)
+		good := newTestBackend(t, http.StatusOK, "good", &goodHits)
+
+		// Round-robin первым выбирает второй бэкенд, поэтому POST попадает на неисправный
+		proxy := handler.NewProxyHandler(balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{
+			balancerDomain.NewBackend(good.URL, 1),
+			balancerDomain.NewBackend(bad.URL, 1),
+		}), config.ProxyConfig{
+			Retry: config.RetryConfig{
+				MaxAttempts: 3,
+				StatusCodes: []int{http.StatusServiceUnavailable},
+			},
+		})
+
+		rec := httptest.NewRecorder()
+		proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("x")))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "bad:x", rec.Body.String())
+		assert.Equal(t, int64(1), badHits.Load())
+		assert.Equal(t, int64(0), goodHits.Load())
+	})
+
+	t.Run("Last attempt is returned when no backend remains", func(t *testing.T) {
+		retryCfg := config.ProxyConfig{Retry: config.RetryConfig{
+			MaxAttempts: 3,
+			StatusCodes: []int{http.StatusServiceUnavailable},
+		}}
+
+		// Ответ бэкенда передается клиенту как есть
+		var hits atomic.Int64
+		single := newTestBackend(t, http.StatusServiceUnavailable, "single", &hits)
+		only := handler.NewProxyHandler(balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{
+			balancerDomain.NewBackend(single.URL, 1),
+		}), retryCfg)
+		rec := httptest.NewRecorder()
+		only.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "single:", rec.Body.String())
+		assert.Equal(t, int64(1), hits.Load())
+
+		// Ошибка соединения — это 502, а не отсутствие бэкенда
+		unreachable := handler.NewProxyHandler(balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{
+			balancerDomain.NewBackend(dead.URL, 1),
+		}), retryCfg)
+		rec = httptest.NewRecorder()
+		unreachable.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusBadGateway, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Backend request failed")
+	})
+}
+
+// TestProxyHeaders — проверяет удаление hop-by-hop заголовков, X-Forwarded-* и трейлеры
+func TestProxyHeaders(t *testing.T) {
+	var got http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("Trailer", "X-Checksum")
+		w.Header().Set("Keep-Alive", "timeout=5")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, "ok")
+		w.Header().Set("X-Checksum", "abc")
+	}))
+	t.Cleanup(backend.Close)
+
+	proxy := handler.NewProxyHandler(
+		balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{balancerDomain.NewBackend(backend.URL, 1)}),
+		config.ProxyConfig{},
+	)
+	front := httptest.NewServer(proxy)
+	t.Cleanup(front.Close)
+
+	req, _ := http.NewRequest(http.MethodGet, front.URL+"/path?q=1", nil)
+	req.Header.Set("Connection", "X-Secret")
+	req.Header.Set("X-Secret", "hop")
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	assert.Equal(t, "ok", string(body))
+	assert.Empty(t, got.Get("X-Secret"))
+	assert.Equal(t, "10.0.0.1, 127.0.0.1", got.Get("X-Forwarded-For"))
+	assert.Equal(t, "http", got.Get("X-Forwarded-Proto"))
+	assert.Equal(t, strings.TrimPrefix(front.URL, "http://"), got.Get("X-Forwarded-Host"))
+	assert.Empty(t, resp.Header.Get("Keep-Alive"))
+	assert.Equal(t, "abc", resp.Trailer.Get("X-Checksum"))
+}
+
+// TestProxyPath — проверяет склейку базового пути бэкенда с путем запроса без потери экранирования
+func TestProxyPath(t *testing.T) {
+	var got string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.RequestURI
+	}))
+	t.Cleanup(backend.Close)
+
+	tests := []struct {
+		name string
+		base string
+		path string
+		want string
+	}{
+		{name: "Plain path", base: "", path: "/a/b?q=1", want: "/a/b?q=1"},
+		{name: "Base path", base: "/api/", path: "/a/b", want: "/api/a/b"},
+		{name: "Escaped slash is kept", base: "", path: "/files/a%2Fb", want: "/files/a%2Fb"},
+		{name: "Escaped slash with base path", base: "/api", path: "/files/a%2Fb", want: "/api/files/a%2Fb"},
+		{name: "Escaped base path", base: "/v%2F1/", path: "/a%2Fb", want: "/v%2F1/a%2Fb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := handler.NewProxyHandler(
+				balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{balancerDomain.NewBackend(backend.URL+tt.base, 1)}),
+				config.ProxyConfig{},
+			)
+			rec := httptest.NewRecorder()
+			proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestProxyStreaming — проверяет, что события SSE доходят до клиента без буферизации
+func TestProxyStreaming(t *testing.T) {
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: first\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+		_, _ = io.WriteString(w, "data: second\n\n")
+	}))
+	t.Cleanup(backend.Close)
+
+	proxy := handler.NewProxyHandler(
+		balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{balancerDomain.NewBackend(backend.URL, 1)}),
+		config.ProxyConfig{},
+	)
+	front := httptest.NewServer(proxy)
+	t.Cleanup(front.Close)
+
+	resp, err := http.Get(front.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	// Первое событие должно прийти до того, как бэкенд завершит ответ
+	buf := make([]byte, len("data: first\n\n"))
+	_, err = io.ReadFull(resp.Body, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "data: first\n\n", string(buf))
+	close(release)
+
+	rest, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "data: second\n\n", string(rest))
+}
+
+// TestProxyUpgrade — проверяет туннелирование соединения после Upgrade и учет активных соединений
+func TestProxyUpgrade(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "echo" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		_ = buf.Flush()
+		_, _ = io.Copy(conn, buf)
+	}))
+	t.Cleanup(backend.Close)
+
+	b := balancerDomain.NewBackend(backend.URL, 1)
+	proxy := handler.NewProxyHandler(
+		balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{b}),
+		config.ProxyConfig{},
+	)
+	front := httptest.NewServer(proxy)
+	t.Cleanup(front.Close)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(front.URL, "http://"))
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	_, err = io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+	assert.NoError(t, err)
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, int64(1), b.GetActiveConnections())
+
+	_, err = io.WriteString(conn, "ping")
+	assert.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(br, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+
+	// После закрытия туннеля соединение перестает учитываться
+	conn.Close()
+	assert.Eventually(t, func() bool {
+		return b.GetActiveConnections() == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+// TestProxyClientCancel — проверяет, что отмена запроса клиентом не учитывается как неудача бэкенда
+func TestProxyClientCancel(t *testing.T) {
+	started, finish := make(chan struct{}), make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-finish:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { close(finish) })
+
+	backend := balancerDomain.NewBackend(slow.URL, 1)
+	backend.SetHealthThresholds(1, 1)
+	backend.SetCircuitBreaker(balancerDomain.NewCircuitBreaker(balancerDomain.CircuitBreakerSettings{MinRequests: 1}))
+	backend.SetAdaptiveLimit(balancerDomain.NewAdaptiveLimit(balancerDomain.AdaptiveLimitSettings{InitialLimit: 10}))
+	proxy := handler.NewProxyHandler(balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{backend}), config.ProxyConfig{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+	assert.True(t, backend.IsAlive())
+	assert.Equal(t, balancerDomain.CircuitClosed, backend.CircuitBreaker().State())
+	assert.Equal(t, 10, backend.AdaptiveLimit().Limit())
+	assert.Equal(t, int64(0), backend.GetActiveConnections())
+}
+
+// TestProxyClientCancelHalfOpen — проверяет, что отмененный клиентом пробный запрос освобождает слот half-open
+func TestProxyClientCancelHalfOpen(t *testing.T) {
+	started := make(chan struct{}, 1)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	t.Cleanup(slow.Close)
+
+	cb := balancerDomain.NewCircuitBreaker(balancerDomain.CircuitBreakerSettings{
+		MinRequests:      1,
+		Cooldown:         20 * time.Millisecond,
+		HalfOpenRequests: 1,
+	})
+	backend := balancerDomain.NewBackend(slow.URL, 1)
+	backend.SetCircuitBreaker(cb)
+	backend.RecordResult(true, time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, balancerDomain.CircuitHalfOpen, cb.State())
+
+	proxy := handler.NewProxyHandler(balancer.NewRoundRobinBalancer([]*balancerDomain.Backend{backend}), config.ProxyConfig{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	proxy.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+	// Цепь по-прежнему в half-open, и бэкенд принимает новый пробный запрос
+	assert.Equal(t, balancerDomain.CircuitHalfOpen, cb.State())
+	assert.True(t, backend.IsAvailable())
+	assert.True(t, backend.AllowRequest())
+}
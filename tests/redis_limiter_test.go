@@ -0,0 +1,349 @@
+package tests
+
+import (
+	"CloudCamp/internal/config"
+	"CloudCamp/internal/handler"
+	"CloudCamp/internal/limiter"
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis — RESP-сервер в процессе теста. Lua не интерпретируется: скрипт лимитера
+// распознается по вызову HMGET и выполняется эквивалентной реализацией на Go под общей блокировкой,
+// что воспроизводит атомарность EVAL
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+
+	mu       sync.Mutex
+	scripts  map[string]bool       // SHA1 загруженных скриптов
+	buckets  map[string][2]float64 // ключ -> {tokens, ts (мкс)}
+	commands map[string]int        // число выполненных команд по имени
+	conns    map[net.Conn]struct{}
+}
+
+// newFakeRedis запускает сервер на свободном порту
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	f := &fakeRedis{
+		ln:       ln,
+		password: password,
+		scripts:  make(map[string]bool),
+		buckets:  make(map[string][2]float64),
+		commands: make(map[string]int),
+		conns:    make(map[net.Conn]struct{}),
+	}
+	go f.serve()
+	t.Cleanup(f.Close)
+	return f
+}
+
+// Addr возвращает адрес сервера
+func (f *fakeRedis) Addr() string {
+	return f.ln.Addr().String()
+}
+
+// Close останавливает сервер и разрывает соединения
+func (f *fakeRedis) Close() {
+	_ = f.ln.Close()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for conn := range f.conns {
+		_ = conn.Close()
+	}
+}
+
+// Commands возвращает число выполненных команд с указанным именем
+func (f *fakeRedis) Commands(name string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.commands[name]
+}
+
+// serve принимает соединения до закрытия listener
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.conns[conn] = struct{}{}
+		f.mu.Unlock()
+		go f.handle(conn)
+	}
+}
+
+// handle обрабатывает команды одного соединения
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		name := strings.ToUpper(args[0])
+		f.mu.Lock()
+		f.commands[name]++
+		f.mu.Unlock()
+
+		var reply string
+		switch {
+		case name == "AUTH":
+			authed = len(args) == 2 && args[1] == f.password
+			reply = "+OK\r\n"
+			if !authed {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case name == "PING":
+			reply = "+PONG\r\n"
+		case name == "SELECT":
+			reply = "+OK\r\n"
+		case name == "EVAL" || name == "EVALSHA":
+			reply = f.eval(name, args[1:])
+		default:
+			reply = fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+		}
+
+		if _, err = io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// eval выполняет скрипт лимитера: аргументы script|sha, numkeys, KEYS..., ARGV...
+func (f *fakeRedis) eval(name string, args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if name == "EVAL" {
+		if !strings.Contains(args[0], "HMGET") {
+			return "-ERR unsupported script\r\n"
+		}
+		sum := sha1.Sum([]byte(args[0]))
+		f.scripts[hex.EncodeToString(sum[:])] = true
+	} else if !f.scripts[args[0]] {
+		return "-NOSCRIPT No matching script. Please use EVAL.\r\n"
+	}
+
+	numkeys, _ := strconv.Atoi(args[1])
+	keys, argv := args[2:2+numkeys], args[2+numkeys:]
+	now := float64(time.Now().UnixMicro())
+
+	type bucket struct {
+		index             int
+		key               string
+		rate, per, tokens float64
+	}
+	var buckets []*bucket
+	for i, key := range keys {
+		rate, _ := strconv.ParseFloat(argv[2*i], 64)
+		per, _ := strconv.ParseFloat(argv[2*i+1], 64)
+		tokens := rate
+		if state, ok := f.buckets[key]; ok {
+			tokens = math.Min(rate, state[0]+math.Max(0, now-state[1])*rate/per)
+		}
+		buckets = append(buckets, &bucket{index: i + 1, key: key, rate: rate, per: per, tokens: tokens})
+	}
+	wait := func(b *bucket, tokens float64) int64 {
+		return int64(math.Ceil(math.Max(0, tokens-b.tokens) * b.per / b.rate))
+	}
+	reply := func(allowed int, b *bucket) string {
+		return fmt.Sprintf("*6\r\n:%d\r\n:%d\r\n:%d\r\n:%d\r\n:%d\r\n:%d\r\n",
+			allowed, int64(b.rate), int64(math.Max(0, math.Floor(b.tokens))), wait(b, b.rate), wait(b, 1), b.index)
+	}
+
+	var denied *bucket
+	for _, b := range buckets {
+		if b.tokens < 1 && (denied == nil || wait(b, 1) > wait(denied, 1)) {
+			denied = b
+		}
+	}
+	if denied != nil {
+		return reply(0, denied)
+	}
+
+	var result *bucket
+	for _, b := range buckets {
+		b.tokens--
+		f.buckets[b.key] = [2]float64{b.tokens, now}
+		if result == nil || math.Floor(b.tokens) < math.Floor(result.tokens) {
+			result = b
+		}
+	}
+	return reply(1, result)
+}
+
+// readCommand читает команду клиента — массив bulk-строк
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("bad command header %q", line)
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+// newRedisLimiter создает распределенный лимитер с локальными настройками лимитов
+func newRedisLimiter(addr, password, policy string, limits *limiter.MemoryRateLimiter) *limiter.RedisRateLimiter {
+	return limiter.NewRedisRateLimiter(config.RedisConfig{
+		Addr:       addr,
+		Password:   password,
+		KeyPrefix:  "test:",
+		Timeout:    time.Second,
+		PoolSize:   4,
+		FailPolicy: policy,
+	}, limits)
+}
+
+// TestRedisRateLimiterSharedAcrossReplicas — проверяет, что реплики делят один лимит клиента
+func TestRedisRateLimiterSharedAcrossReplicas(t *testing.T) {
+	srv := newFakeRedis(t, "secret")
+
+	var replicas []*limiter.RedisRateLimiter
+	for i := 0; i < 3; i++ {
+		limits := limiter.NewMemoryRateLimiter()
+		assert.NoError(t, limits.SetClientLimit("client1", 4, time.Minute))
+
+		l := newRedisLimiter(srv.Addr(), "secret", config.FailPolicyClosed, limits)
+		t.Cleanup(func() { _ = l.Close() })
+		replicas = append(replicas, l)
+	}
+
+	allowed := 0
+	for i := 0; i < 12; i++ {
+		if replicas[i%3].Allow("client1").Allowed {
+			allowed++
+		}
+	}
+	assert.Equal(t, 4, allowed, "three replicas must share one bucket instead of tripling the limit")
+
+	// Клиент без лимитов не требует обращения к Redis
+	before := srv.Commands("EVALSHA")
+	assert.True(t, replicas[0].Allow("unknown").Allowed)
+	assert.Equal(t, before, srv.Commands("EVALSHA"))
+
+	// Скрипт загружается через EVAL один раз, далее вызывается по SHA
+	assert.Equal(t, 1, srv.Commands("EVAL"))
+}
+
+// TestRedisRateLimiterGlobalLimit — проверяет общий глобальный лимит
+func TestRedisRateLimiterGlobalLimit(t *testing.T) {
+	srv := newFakeRedis(t, "")
+
+	limits := limiter.NewMemoryRateLimiter()
+	assert.NoError(t, limits.SetLimit("global", 2, time.Minute))
+	l := newRedisLimiter(srv.Addr(), "", config.FailPolicyClosed, limits)
+	defer l.Close()
+
+	d := l.Allow("a")
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 2, d.Limit)
+	assert.Equal(t, 1, d.Remaining)
+	assert.Zero(t, d.RetryAfter)
+	assert.True(t, l.Allow("b").Allowed)
+
+	d = l.Allow("c")
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+	assert.InDelta(t, 30*time.Second, d.RetryAfter, float64(time.Second))
+	assert.InDelta(t, time.Minute, d.Reset, float64(time.Second))
+}
+
+// TestRedisRateLimiterFailPolicy — проверяет поведение при недоступном хранилище
+func TestRedisRateLimiterFailPolicy(t *testing.T) {
+	srv := newFakeRedis(t, "secret")
+	limits := limiter.NewMemoryRateLimiter()
+	assert.NoError(t, limits.SetClientLimit("client1", 100, time.Minute))
+
+	open := newRedisLimiter(srv.Addr(), "secret", config.FailPolicyOpen, limits)
+	defer open.Close()
+	closed := newRedisLimiter(srv.Addr(), "secret", config.FailPolicyClosed, limits)
+	defer closed.Close()
+	wrongPassword := newRedisLimiter(srv.Addr(), "wrong", config.FailPolicyClosed, limits)
+	defer wrongPassword.Close()
+
+	assert.True(t, open.Allow("client1").Allowed)
+	assert.True(t, closed.Allow("client1").Allowed)
+	assert.False(t, wrongPassword.Allow("client1").Allowed)
+
+	srv.Close()
+
+	assert.True(t, open.Allow("client1").Allowed)
+	d := closed.Allow("client1")
+	assert.False(t, d.Allowed)
+	assert.Equal(t, time.Second, d.RetryAfter)
+
+	// Отказ из-за недоступного хранилища сообщает клиенту, когда повторить запрос
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Client-ID", "client1")
+	handler.NewRateLimiterMiddleware(closed).Middleware(http.NotFoundHandler()).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+}
+
+// TestRedisRateLimiterPoolSize — проверяет, что одновременные запросы не открывают больше pool_size соединений
+func TestRedisRateLimiterPoolSize(t *testing.T) {
+	srv := newFakeRedis(t, "secret")
+	limits := limiter.NewMemoryRateLimiter()
+	assert.NoError(t, limits.SetClientLimit("client1", 1000, time.Minute))
+
+	l := newRedisLimiter(srv.Addr(), "secret", config.FailPolicyClosed, limits)
+	defer l.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.True(t, l.Allow("client1").Allowed)
+		}()
+	}
+	wg.Wait()
+
+	// Каждое новое соединение начинается с AUTH
+	assert.LessOrEqual(t, srv.Commands("AUTH"), 4)
+	assert.Equal(t, 50, srv.Commands("EVALSHA"))
+}
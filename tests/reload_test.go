@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"CloudCamp/internal/app"
+	"CloudCamp/internal/background"
+	"CloudCamp/internal/config"
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestApplyConfig — проверяет применение новой конфигурации без пересоздания неизмененных бэкендов
+func TestApplyConfig(t *testing.T) {
+	cfg := &config.Config{
+		Balancer: config.BalancerConfig{
+			Strategy: "round-robin",
+			Backends: []config.BackendConfig{{URL: "http://a:1"}, {URL: "http://b:2"}},
+		},
+		RateLimiter: config.RateLimitConfig{
+			Enabled: true,
+			Rate:    10,
+			Period:  time.Second,
+			Clients: map[string]config.ClientLimit{
+				"client1": {RateLimit: 5, Period: time.Minute},
+				"client2": {RateLimit: 7, Period: time.Minute},
+			},
+		},
+	}
+	server, err := app.NewServer(cfg)
+	assert.NoError(t, err)
+	assert.NoError(t, server.ApplyConfig(cfg))
+
+	kept := server.GetBackends()[0]
+	kept.IncrementConnections()
+
+	newCfg := *cfg
+	newCfg.Balancer = config.BalancerConfig{
+		Strategy: "least-connections",
+		Backends: []config.BackendConfig{{URL: "http://a:1"}, {URL: "http://c:3", Weight: 2}},
+	}
+	newCfg.RateLimiter.Clients = map[string]config.ClientLimit{
+		"client1": {RateLimit: 9, Period: time.Minute},
+	}
+	assert.NoError(t, server.ApplyConfig(&newCfg))
+
+	backends := server.GetBackends()
+	assert.Len(t, backends, 2)
+	assert.Same(t, kept, backends[0])
+	assert.Equal(t, int64(1), backends[0].GetActiveConnections())
+	assert.Equal(t, "http://c:3", backends[1].URL)
+	assert.Equal(t, 2, backends[1].Weight)
+
+	// Стратегия заменена: least-connections выбирает бэкенд без соединений
+	assert.Equal(t, "http://c:3", server.GetBalancer().NextBackend().URL)
+
+	rate, _ := server.GetLimiter().GetLimit("client1")
+	assert.Equal(t, 9, rate)
+	rate, _ = server.GetLimiter().GetLimit("client2")
+	assert.Equal(t, 0, rate)
+
+	// Неизвестная стратегия отклоняется целиком
+	badCfg := newCfg
+	badCfg.Balancer.Strategy = "unknown"
+	assert.Error(t, server.ApplyConfig(&badCfg))
+	assert.Len(t, server.GetBackends(), 2)
+}
+
+// TestConfigWatcher — проверяет перезагрузку при изменении файла конфигурации
+func TestConfigWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("env: test\n"), 0644))
+
+	var reloads atomic.Int64
+	watcher := background.NewConfigWatcher(path, 10*time.Millisecond, func() error {
+		reloads.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	watcher.Start(ctx)
+	defer func() {
+		cancel()
+		watcher.Wait()
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, int64(0), reloads.Load())
+
+	assert.NoError(t, os.WriteFile(path, []byte("env: development\n"), 0644))
+	assert.Eventually(t, func() bool {
+		return reloads.Load() == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+// TestConfigWatcherWithoutFile — проверяет, что без файла конфигурации он не опрашивается
+func TestConfigWatcherWithoutFile(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	var reloads atomic.Int64
+	watcher := background.NewConfigWatcher("", 5*time.Millisecond, func() error {
+		reloads.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	watcher.Start(ctx)
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+	watcher.Wait()
+
+	assert.Equal(t, int64(0), reloads.Load())
+	assert.NotContains(t, logs.String(), "Failed to stat config file")
+}
@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"CloudCamp/internal/app"
+	"CloudCamp/internal/config"
+	limiterDomain "CloudCamp/internal/domain/limiter"
+	"CloudCamp/internal/handler"
+	"CloudCamp/internal/limiter"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFileClientStore — проверяет сохранение, восстановление и сжатие журнала клиентов
+func TestFileClientStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "clients.log")
+
+	store, err := limiter.OpenFileClientStore(path)
+	assert.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, store.Save("c1", i+1, time.Minute))
+	}
+	assert.NoError(t, store.Save("c2", 2, time.Second))
+	assert.NoError(t, store.Delete("c2"))
+	assert.NoError(t, store.Close())
+	assert.Error(t, store.Save("c3", 1, time.Second))
+
+	// Имитируем аварийную остановку посреди записи
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	assert.NoError(t, err)
+	_, err = f.WriteString(`{"op":"set","client_id":"c3","ra`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	store, err = limiter.OpenFileClientStore(path)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	records := store.Load()
+	assert.Len(t, records, 2)
+	assert.Equal(t, "c1", records[0].ClientID)
+	assert.Equal(t, 5, records[0].Rate)
+	assert.Equal(t, time.Minute, records[0].Period)
+	assert.Equal(t, "c2", records[1].ClientID)
+	assert.True(t, records[1].Deleted)
+
+	// После открытия журнал сжат до одной строки на клиента
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"))
+}
+
+// TestFileClientStoreBatch — проверяет, что пакет изменений восстанавливается целиком или не восстанавливается
+func TestFileClientStoreBatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clients.log")
+
+	store, err := limiter.OpenFileClientStore(path)
+	assert.NoError(t, err)
+	assert.NoError(t, store.SaveAll([]limiterDomain.ClientRecord{
+		{ClientID: "c1", Rate: 1, Period: time.Second},
+		{ClientID: "c2", Rate: 2, Period: time.Minute},
+	}))
+	assert.NoError(t, store.Close())
+
+	// Пакет с некорректным изменением и оборванный пакет не применяются даже частично
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	assert.NoError(t, err)
+	_, err = f.WriteString(`{"op":"batch","entries":[{"op":"set","client_id":"c3","rate":1,"period":"1s"},{"op":"set","client_id":"","rate":1,"period":"1s"}]}` + "\n")
+	assert.NoError(t, err)
+	_, err = f.WriteString(`{"op":"batch","entries":[{"op":"set","client_id":"c4","rate":1,"period":"1s"},{"op":"set","cli`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	store, err = limiter.OpenFileClientStore(path)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	assert.Equal(t, []limiterDomain.ClientRecord{
+		{ClientID: "c1", Rate: 1, Period: time.Second},
+		{ClientID: "c2", Rate: 2, Period: time.Minute},
+	}, store.Load())
+}
+
+// TestFileClientStoreLargeBatch — проверяет, что журнал с пакетом длиннее 64 КБ открывается
+func TestFileClientStoreLargeBatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clients.log")
+
+	store, err := limiter.OpenFileClientStore(path)
+	assert.NoError(t, err)
+	records := make([]limiterDomain.ClientRecord, 2000)
+	for i := range records {
+		records[i] = limiterDomain.ClientRecord{ClientID: fmt.Sprintf("client-%04d", i), Rate: i + 1, Period: time.Minute}
+	}
+	assert.NoError(t, store.SaveAll(records))
+	assert.NoError(t, store.Close())
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Greater(t, info.Size(), int64(64<<10))
+
+	store, err = limiter.OpenFileClientStore(path)
+	assert.NoError(t, err)
+	defer store.Close()
+	assert.Equal(t, records, store.Load())
+}
+
+// TestClientImportStoreFailure — проверяет, что при ошибке хранилища импорт не применяется
+func TestClientImportStoreFailure(t *testing.T) {
+	store, err := limiter.OpenFileClientStore(filepath.Join(t.TempDir(), "clients.log"))
+	assert.NoError(t, err)
+	assert.NoError(t, store.Close())
+
+	rl := limiter.NewMemoryRateLimiter()
+	h := handler.NewClientHandler(rl, store)
+	code := doAdmin(t, h, http.MethodPost, "/clients/import",
+		`[{"client_id":"c1","rate":1,"period":"1s"},{"client_id":"c2","rate":2,"period":"1s"}]`, nil)
+	assert.Equal(t, http.StatusInternalServerError, code)
+	_, exists := rl.Client("c1")
+	assert.False(t, exists)
+}
+
+// TestClientStorePrecedence — проверяет, что изменения из API переживают перезапуск и имеют приоритет над конфигурацией
+func TestClientStorePrecedence(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "clients.log")
+	cfg := &config.Config{
+		Server: config.ServerConfig{Port: 8088},
+		Balancer: config.BalancerConfig{
+			Strategy: "round-robin",
+			Backends: []config.BackendConfig{{URL: newTestBackend(t, http.StatusOK, "ok", nil).URL}},
+		},
+		RateLimiter: config.RateLimitConfig{
+			Enabled: true,
+			Rate:    100,
+			Period:  time.Second,
+			Clients: map[string]config.ClientLimit{
+				"yaml_changed": {RateLimit: 5, Period: time.Minute},
+				"yaml_deleted": {RateLimit: 5, Period: time.Minute},
+				"yaml_only":    {RateLimit: 5, Period: time.Minute},
+			},
+			Store: config.ClientStoreConfig{Type: config.ClientStoreFile, Path: storePath},
+		},
+	}
+
+	// Изменения через API в предыдущем запуске
+	store, err := limiter.OpenFileClientStore(storePath)
+	assert.NoError(t, err)
+	h := handler.NewClientHandler(limiter.NewMemoryRateLimiter(), store)
+	assert.Equal(t, http.StatusCreated, doAdmin(t, h, http.MethodPut, "/clients/yaml_changed", `{"rate":9,"period":"1m"}`, nil))
+	assert.Equal(t, http.StatusCreated, doAdmin(t, h, http.MethodPost, "/clients", `{"client_id":"api_only","rate":3,"period":"1s"}`, nil))
+	assert.NoError(t, store.Delete("yaml_deleted"))
+	assert.NoError(t, store.Close())
+
+	server, err := app.NewServer(cfg)
+	assert.NoError(t, err)
+	go server.Run()
+	defer server.Shutdown()
+	if err = waitForServerReady("http://localhost:8088", 2*time.Second); err != nil {
+		t.Fatalf("Server did not start in time: %v", err)
+	}
+
+	rl := server.GetLimiter()
+	rate, _ := rl.GetLimit("yaml_changed")
+	assert.Equal(t, 9, rate)
+	rate, _ = rl.GetLimit("yaml_deleted")
+	assert.Equal(t, 0, rate)
+	rate, _ = rl.GetLimit("yaml_only")
+	assert.Equal(t, 5, rate)
+	rate, per := rl.GetLimit("api_only")
+	assert.Equal(t, 3, rate)
+	assert.Equal(t, time.Second, per)
+
+	// Перезагрузка конфигурации не перезаписывает клиентов из хранилища
+	newCfg := *cfg
+	newCfg.RateLimiter.Clients = map[string]config.ClientLimit{
+		"yaml_changed": {RateLimit: 50, Period: time.Minute},
+		"yaml_deleted": {RateLimit: 50, Period: time.Minute},
+		"yaml_only":    {RateLimit: 50, Period: time.Minute},
+	}
+	assert.NoError(t, server.ApplyConfig(&newCfg))
+
+	rate, _ = rl.GetLimit("yaml_changed")
+	assert.Equal(t, 9, rate)
+	rate, _ = rl.GetLimit("yaml_deleted")
+	assert.Equal(t, 0, rate)
+	rate, _ = rl.GetLimit("yaml_only")
+	assert.Equal(t, 50, rate)
+}